@@ -0,0 +1,176 @@
+// Command header-parser parses C++ headers and writes their declarations as json, see
+// ymdCppHeaderParser.MarshalUpstreamJson for the layout.
+//
+//	header-parser -c TCLASS -e TENUM -f TFUNC -p TPROPERTY include/*.h
+//
+// Without -o the json of the single input file is written to stdout. With -o every file gets a .json file
+// of the same base name in the output directory, e.g. -o out include/a.h writes out/a.json.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/orestonce/header-parser-go/ymdCppHeaderParser"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run returns the exit code: 0 on success, 1 if a file could not be parsed, 2 for a usage error
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet(`header-parser`, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var options ymdCppHeaderParser.ParserOptions
+	flags.StringVar(&options.ClassNameMacro, `c`, ``, `The macro name used to annotate classes`)
+	flags.StringVar(&options.EnumNameMacro, `e`, ``, `The macro name used to annotate enums`)
+	flags.StringVar(&options.FunctionNameMacro, `f`, ``, `The macro name used to annotate functions`)
+	flags.StringVar(&options.PropertyNameMacro, `p`, ``, `The macro name used to annotate properties`)
+	flags.BoolVar(&options.AnnotatedOnly, `a`, false, `Only report the annotated declarations`)
+	flags.BoolVar(&options.Recover, `r`, false, `Skip the statements which can not be parsed and report all the errors`)
+	exportMacros := flags.String(`x`, ``, `Comma separated export macros which may precede class names, e.g. MYLIB_API`)
+	outputDir := flags.String(`o`, ``, `Write one .json file per input into this directory instead of stdout, required for several files`)
+	indent := flags.String(`indent`, `    `, `Indentation of the json, empty for compact output`)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: header-parser [flags] file|glob...\n")
+		flags.PrintDefaults()
+	}
+	patterns, err := parseFlags(flags, args)
+	if err != nil {
+		return 2
+	}
+	if *exportMacros != `` {
+		options.ExportMacros = strings.Split(*exportMacros, `,`)
+	}
+
+	files, err := expandGlobs(patterns)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if len(files) == 0 {
+		flags.Usage()
+		return 2
+	}
+	targets, err := outputTargets(files, *outputDir)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	exitCode := 0
+	for idx, file := range files {
+		output, err := parseFile(file, options, *indent)
+		if err != nil {
+			exitCode = 1
+			printError(stderr, err)
+			if output == `` {
+				continue
+			}
+		}
+		if *outputDir == `` {
+			fmt.Fprintln(stdout, output)
+			continue
+		}
+		if err = os.MkdirAll(*outputDir, 0755); err == nil {
+			err = ioutil.WriteFile(targets[idx], []byte(output+"\n"), 0644)
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			exitCode = 1
+		}
+	}
+	return exitCode
+}
+
+// parseFlags allows the flags in front of and behind the files, e.g. a.h -c TCLASS.
+// The flag package stops at the first file, so the parsing continues behind every file. Everything behind -- is a file.
+func parseFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	var patterns []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := flags.Args()
+		if len(rest) == 0 {
+			return patterns, nil
+		}
+		if consumed := args[:len(args)-len(rest)]; len(consumed) != 0 && consumed[len(consumed)-1] == `--` {
+			return append(patterns, rest...), nil
+		}
+		patterns = append(patterns, rest[0])
+		args = rest[1:]
+	}
+}
+
+func expandGlobs(patterns []string) ([]string, error) {
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("%v: no such file", pattern)
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// outputTargets returns the .json file of every input in the output directory. The base name of the input is used
+// so that no input path such as ../a.h or /usr/include/a.h can point outside the directory.
+func outputTargets(files []string, outputDir string) ([]string, error) {
+	if outputDir == `` {
+		if len(files) > 1 {
+			return nil, fmt.Errorf("-o is required for %v files, stdout takes a single json document", len(files))
+		}
+		return nil, nil
+	}
+	var targets []string
+	sources := map[string]string{}
+	for _, file := range files {
+		base := filepath.Base(file)
+		target := filepath.Join(outputDir, strings.TrimSuffix(base, filepath.Ext(base))+`.json`)
+		if source, ok := sources[target]; ok {
+			return nil, fmt.Errorf("%v and %v would both be written to %v", source, file, target)
+		}
+		sources[target] = file
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// parseFile returns the json of the file, in recovery mode it is returned along with the diagnostics
+func parseFile(file string, options ymdCppHeaderParser.ParserOptions, indent string) (string, error) {
+	input, err := ioutil.ReadFile(file)
+	if err != nil {
+		return ``, err
+	}
+	p := ymdCppHeaderParser.NewParserWithOptions(input, options)
+	p.FileName = file
+	parsed, err := p.ParseAll()
+	if err != nil && !options.Recover {
+		return ``, err
+	}
+	return ymdCppHeaderParser.MarshalUpstreamJson(parsed, indent), err
+}
+
+func printError(stderr io.Writer, err error) {
+	var diagnostics ymdCppHeaderParser.Diagnostics
+	if errors.As(err, &diagnostics) {
+		for _, one := range diagnostics {
+			fmt.Fprintf(stderr, "%v, skipped %v:%v to %v:%v\n", one.Error(),
+				one.SkippedStartLine, one.SkippedStartColumn, one.SkippedEndLine, one.SkippedEndColumn)
+		}
+		return
+	}
+	fmt.Fprintln(stderr, err)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeHeaders(t *testing.T, headers map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range headers {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRun(t *testing.T) {
+	dir := writeHeaders(t, map[string]string{
+		`a.h`: `TCLASS() class A {}; class B {};`,
+		`b.h`: `TENUM() enum E { X };`,
+	})
+	outputDir := t.TempDir()
+	var stdout, stderr bytes.Buffer
+	code := run([]string{`-c`, `TCLASS`, `-e`, `TENUM`, `-a`, `-indent=`, `-o`, outputDir, filepath.Join(dir, `*.h`)}, &stdout, &stderr)
+	if code != 0 || stdout.Len() != 0 {
+		t.Fatalf("exit code %v: %v", code, stderr.String())
+	}
+	for name, want := range map[string]string{
+		`a.json`: `[{"type":"class","line":1,"meta":{},"isstruct":false,"name":"A","members":[]}]
+`,
+		`b.json`: `[{"type":"enum","line":1,"meta":{},"cxxclass":false,"name":"E","members":[{"key":"X"}]}]
+`,
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(outputDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Fatalf("unexpected output %v", string(data))
+		}
+	}
+
+	// stdout takes a single file
+	code = run([]string{`-indent=`, filepath.Join(dir, `*.h`)}, &stdout, &stderr)
+	if code != 2 || stdout.Len() != 0 {
+		t.Fatalf("exit code %v: %v", code, stdout.String())
+	}
+	if !strings.Contains(stderr.String(), `-o is required for 2 files`) {
+		t.Fatalf("unexpected error %v", stderr.String())
+	}
+}
+
+func TestRun_ExportMacros(t *testing.T) {
+	dir := writeHeaders(t, map[string]string{`a.h`: `class MYLIB_API A : public B<int> {};`})
+	var stdout, stderr bytes.Buffer
+	code := run([]string{`-x`, `MYLIB_API,OTHER_API`, `-indent=`, filepath.Join(dir, `a.h`)}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit code %v: %v", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), `"name":"A","parents":[{"access":"public","name":{"type":"template"`) {
+		t.Fatalf("unexpected output %v", stdout.String())
+	}
+}
+
+func TestRun_OutputDir(t *testing.T) {
+	dir := writeHeaders(t, map[string]string{`a.h`: `int f();`})
+	assert := func(ok bool, a ...interface{}) {
+		if !ok {
+			t.Fatal(a...)
+		}
+	}
+	assert(os.Mkdir(filepath.Join(dir, `sub`), 0755) == nil)
+	assert(ioutil.WriteFile(filepath.Join(dir, `sub`, `b.hpp`), []byte(`int g();`), 0644) == nil)
+	assert(ioutil.WriteFile(filepath.Join(dir, `sub`, `a.h`), []byte(`int h();`), 0644) == nil)
+
+	// The json files are written into the output directory under the base name of their input
+	outputDir := filepath.Join(dir, `out`)
+	var stdout, stderr bytes.Buffer
+	code := run([]string{`-o`, outputDir, filepath.Join(dir, `a.h`), filepath.Join(dir, `out`, `..`, `sub`, `b.hpp`)}, &stdout, &stderr)
+	assert(code == 0 && stdout.Len() == 0, code, stderr.String())
+	data, err := ioutil.ReadFile(filepath.Join(outputDir, `a.json`))
+	assert(err == nil, err)
+	assert(strings.Contains(string(data), `"name": "f"`), string(data))
+	data, err = ioutil.ReadFile(filepath.Join(outputDir, `b.json`))
+	assert(err == nil, err)
+	assert(strings.Contains(string(data), `"name": "g"`), string(data))
+	entries, err := ioutil.ReadDir(outputDir)
+	assert(err == nil && len(entries) == 2, entries, err)
+
+	// Two inputs of the same base name are rejected before anything is written
+	outputDir = filepath.Join(dir, `other`)
+	code = run([]string{`-o`, outputDir, filepath.Join(dir, `a.h`), filepath.Join(dir, `sub`, `a.h`)}, &stdout, &stderr)
+	assert(code == 2, code)
+	assert(strings.Contains(stderr.String(), `would both be written to `+filepath.Join(outputDir, `a.json`)), stderr.String())
+	_, err = os.Stat(outputDir)
+	assert(os.IsNotExist(err), err)
+}
+
+func TestRun_ParseError(t *testing.T) {
+	dir := writeHeaders(t, map[string]string{`bad.h`: "int a;\nclass : A { };\nint b;"})
+	file := filepath.Join(dir, `bad.h`)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{file}, &stdout, &stderr)
+	if code != 1 || stdout.Len() != 0 {
+		t.Fatalf("exit code %v: %v", code, stdout.String())
+	}
+	if !strings.HasPrefix(stderr.String(), file+`:2:7: Missing class name`) {
+		t.Fatalf("unexpected error %v", stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	code = run([]string{`-r`, `-indent=`, file}, &stdout, &stderr)
+	if code != 1 || !strings.Contains(stdout.String(), `"name":"b"`) {
+		t.Fatalf("exit code %v: %v", code, stdout.String())
+	}
+	if !strings.Contains(stderr.String(), `skipped 2:1 to 2:14`) {
+		t.Fatalf("unexpected error %v", stderr.String())
+	}
+}
+
+func TestRun_Usage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run(nil, &stdout, &stderr); code != 2 {
+		t.Fatalf("exit code %v", code)
+	}
+	if code := run([]string{filepath.Join(os.TempDir(), `does-not-exist-*.h`)}, &stdout, &stderr); code != 2 {
+		t.Fatalf("exit code %v", code)
+	}
+}
+
+func TestRun_FlagsBehindFiles(t *testing.T) {
+	dir := writeHeaders(t, map[string]string{`a.h`: `TCLASS() class A {}; class B {};`})
+	file := filepath.Join(dir, `a.h`)
+	want := `[{"type":"class","line":1,"meta":{},"isstruct":false,"name":"A","members":[]}]
+`
+	for _, args := range [][]string{
+		{`-c`, `TCLASS`, `-a`, `-indent=`, file},
+		{file, `-c`, `TCLASS`, `-a`, `-indent=`},
+		{`-c`, `TCLASS`, file, `-a`, `-indent=`},
+		{`-c`, `TCLASS`, `-a`, `-indent=`, `--`, file},
+	} {
+		var stdout, stderr bytes.Buffer
+		code := run(args, &stdout, &stderr)
+		if code != 0 {
+			t.Fatalf("%v: exit code %v: %v", args, code, stderr.String())
+		}
+		if stdout.String() != want {
+			t.Fatalf("%v: unexpected output %v", args, stdout.String())
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{file, `-unknown`}, &stdout, &stderr); code != 2 {
+		t.Fatalf("exit code %v", code)
+	}
+	if !strings.Contains(stderr.String(), `-unknown`) {
+		t.Fatalf("unexpected error %v", stderr.String())
+	}
+}
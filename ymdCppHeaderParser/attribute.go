@@ -0,0 +1,180 @@
+package ymdCppHeaderParser
+
+type AttributeSyntax string
+
+const (
+	kStandardAttribute AttributeSyntax = `kStandardAttribute` // [[nodiscard]]
+	kGnuAttribute      AttributeSyntax = `kGnuAttribute`      // __attribute__((visibility("default")))
+	kDeclspecAttribute AttributeSyntax = `kDeclspecAttribute` // __declspec(dllexport)
+	kAlignas           AttributeSyntax = `kAlignas`           // alignas(16), the name is alignas
+)
+
+// Attribute is a C++ attribute or a compiler-specific declaration specifier
+type Attribute struct {
+	Syntax    AttributeSyntax `json:",omitempty"`
+	Namespace string          `json:",omitempty"` // gnu of [[gnu::always_inline]] or of [[using gnu: hot]]
+	Name      string          `json:",omitempty"`
+	// Tokens of the argument clause as written, e.g. "use g" including the quotes
+	Arguments []string `json:",omitempty"`
+}
+
+// Attribute returns the attribute of the declaration with the given name, nil if there is none
+func (this *Declaration) Attribute(name string) *Attribute {
+	for _, one := range this.Attributes {
+		if one.Name == name {
+			return one
+		}
+	}
+	return nil
+}
+
+// isAttributeStart tells whether the token begins an attribute specifier
+func (this *Parser) isAttributeStart(token *Token) bool {
+	switch token.Mtoken {
+	case `__attribute__`, `__declspec`, `alignas`:
+		return token.MtokenType == kIdentifier
+	case `[`:
+		var next Token
+		if token.MtokenType != kSymbol || !this.GetToken(&next, false, false) {
+			return false
+		}
+		this.UngetToken(&next)
+		return next.MtokenType == kSymbol && next.Mtoken == `[`
+	}
+	return false
+}
+
+// parseAttributes parses the attribute specifiers at the cursor, they are taken over by the next
+// declaration. It tells whether there were any.
+func (this *Parser) parseAttributes() bool {
+	startPos := this.cursorPos
+	attributes := this.parseAttributeSpecifiers()
+	// Backtracking parses some specifiers again, keep them once
+	if startPos >= this.attributesPos {
+		this.pendingAttributes = append(this.pendingAttributes, attributes...)
+		this.attributesPos = this.cursorPos
+	}
+	return attributes != nil
+}
+
+// takeAttributes returns the attributes parsed for the declaration being parsed
+func (this *Parser) takeAttributes() []*Attribute {
+	attributes := this.pendingAttributes
+	this.pendingAttributes = nil
+	return attributes
+}
+
+// parseAttributeSpecifiers parses the attribute specifiers at the cursor, nil if there are none
+func (this *Parser) parseAttributeSpecifiers() []*Attribute {
+	const funcId = `x4h9cu1b `
+	var attributes []*Attribute
+	for {
+		// Keep the cursor in front of the whitespace behind the last specifier, see TrailingComment
+		mark := Token{MstartPos: this.cursorPos, MstartLine: this.cursorLine}
+		var token Token
+		if !this.GetToken(&token, false, false) || !this.isAttributeStart(&token) {
+			this.UngetToken(&mark)
+			return attributes
+		}
+		if attributes == nil {
+			attributes = []*Attribute{}
+		}
+		switch token.Mtoken {
+		case `[`:
+			this.requireSymbol(`[`)
+			namespace := ``
+			if this.MatchIdentifier(`using`) {
+				// [[using gnu: hot, cold]]
+				var namespaceToken Token
+				if !this.GetIdentifier(&namespaceToken) {
+					this.panicf(funcId, `Expected attribute namespace`)
+				}
+				namespace = namespaceToken.Mtoken
+				this.requireSymbol(`:`)
+			}
+			attributes = append(attributes, this.parseAttributeList(kStandardAttribute, namespace, `]`)...)
+			this.requireSymbol(`]`)
+		case `__attribute__`:
+			this.requireSymbol(`(`)
+			this.requireSymbol(`(`)
+			attributes = append(attributes, this.parseAttributeList(kGnuAttribute, ``, `)`)...)
+			this.requireSymbol(`)`)
+		case `__declspec`:
+			// The attributes are separated by spaces, e.g. __declspec(align(16) dllexport)
+			this.requireSymbol(`(`)
+			for !this.MatchSymbol(`)`) {
+				attributes = append(attributes, this.parseAttribute(kDeclspecAttribute, ``))
+			}
+		case `alignas`:
+			this.requireSymbol(`(`)
+			attributes = append(attributes, &Attribute{
+				Syntax:    kAlignas,
+				Name:      token.Mtoken,
+				Arguments: this.scanArgumentTokens(),
+			})
+		}
+	}
+}
+
+// parseAttributeList parses the comma separated attributes up to the closing symbol and skips it
+func (this *Parser) parseAttributeList(syntax AttributeSyntax, namespace string, closing string) []*Attribute {
+	attributes := []*Attribute{}
+	for !this.MatchSymbol(closing) {
+		if this.MatchSymbol(`,`) {
+			continue
+		}
+		attributes = append(attributes, this.parseAttribute(syntax, namespace))
+		this.MatchSymbol(`...`)
+	}
+	return attributes
+}
+
+// parseAttribute parses a single attribute including its arguments, e.g. gnu::aligned(16)
+func (this *Parser) parseAttribute(syntax AttributeSyntax, namespace string) *Attribute {
+	const funcId = `m7e2qs9d `
+	var nameToken Token
+	// Keywords are valid attribute names, e.g. __attribute__((const))
+	if !this.GetIdentifier(&nameToken) {
+		this.panicf(funcId, `Expected attribute name`)
+	}
+	attribute := &Attribute{
+		Syntax:    syntax,
+		Namespace: namespace,
+		Name:      nameToken.Mtoken,
+	}
+	if this.MatchSymbol(`::`) {
+		if !this.GetIdentifier(&nameToken) {
+			this.panicf(funcId, `Expected attribute name`)
+		}
+		attribute.Namespace = attribute.Name
+		attribute.Name = nameToken.Mtoken
+	}
+	if this.MatchSymbol(`(`) {
+		attribute.Arguments = this.scanArgumentTokens()
+	}
+	return attribute
+}
+
+// scanArgumentTokens returns the tokens behind the ( at the cursor up to the matching ) and skips both
+func (this *Parser) scanArgumentTokens() []string {
+	const funcId = `d0w6pl3t `
+	tokens := []string{}
+	depth := 1
+	var token Token
+	for this.GetToken(&token, false, false) {
+		if token.MtokenType == kSymbol {
+			switch token.Mtoken {
+			case `(`:
+				depth++
+			case `)`:
+				depth--
+				if depth == 0 {
+					return tokens
+				}
+			}
+		}
+		tokens = append(tokens, string(this.input[token.MstartPos:this.cursorPos]))
+	}
+	this.panicf(funcId, `Missing ")"`)
+	return nil
+}
@@ -0,0 +1,67 @@
+package ymdCppHeaderParser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParser_ParseAttributes(t *testing.T) {
+	p := NewParser([]byte(`
+[[nodiscard]] int compute();
+[[deprecated("use g")]] void f();
+[[gnu::always_inline, using_it]] inline void fast();
+__attribute__((visibility("default"))) void exported();
+__declspec(dllexport) __declspec(align(16) noinline) void windows();
+void exit() __attribute__((noreturn));
+int __attribute__((unused)) counter [[maybe_unused]];
+void unused([[maybe_unused]] int a, int b);
+
+struct [[deprecated]] alignas(16) Aligned { int x; };
+class __declspec(dllexport) Exported;
+enum class [[nodiscard]] Result { kOk, kError [[deprecated]] = 2 };
+namespace [[deprecated]] old { }
+[[using gnu: hot, cold]] void tuned();
+template <typename T> [[nodiscard]] T make();
+using Int [[deprecated]] = int;
+`))
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	functions := file.Functions
+	assert(len(functions) == 9, len(functions))
+
+	compute := functions[0]
+	assert(compute.IsNodiscard && len(compute.Attributes) == 1 && compute.Attributes[0].Syntax == kStandardAttribute)
+	deprecated := functions[1].Attribute(`deprecated`)
+	assert(deprecated != nil && strings.Join(deprecated.Arguments, ` `) == `"use g"`)
+	fast := functions[2]
+	assert(fast.IsInline && len(fast.Attributes) == 2)
+	assert(fast.Attributes[0].Namespace == `gnu` && fast.Attributes[0].Name == `always_inline`)
+	visibility := functions[3].Attribute(`visibility`)
+	assert(visibility.Syntax == kGnuAttribute && visibility.Arguments[0] == `"default"`)
+	windows := functions[4].Attributes
+	assert(len(windows) == 3 && windows[0].Name == `dllexport` && windows[0].Syntax == kDeclspecAttribute)
+	assert(windows[1].Name == `align` && windows[1].Arguments[0] == `16` && windows[2].Name == `noinline`)
+	assert(functions[5].Attribute(`noreturn`) != nil && functions[5].Name == `exit`)
+	unused := functions[6]
+	assert(unused.Attributes == nil && len(unused.Arguments[0].Attributes) == 1 && unused.Arguments[1].Attributes == nil)
+	tuned := functions[7].Attributes
+	assert(len(tuned) == 2 && tuned[0].Namespace == `gnu` && tuned[1].Name == `cold`)
+	assert(functions[8].IsTemplate && functions[8].IsNodiscard)
+
+	counter := file.Fields[0]
+	assert(counter.Name == `counter` && len(counter.Attributes) == 2)
+	assert(counter.Attributes[0].Name == `unused` && counter.Attributes[1].Name == `maybe_unused`)
+
+	aligned := file.Classes[0]
+	assert(aligned.Name == `Aligned` && len(aligned.Attributes) == 2)
+	assert(aligned.Attributes[1].Syntax == kAlignas && aligned.Attributes[1].Arguments[0] == `16`)
+	assert(len(aligned.Fields) == 1 && aligned.Fields[0].Attributes == nil)
+	assert(len(file.Classes) == 1)
+
+	result := file.Enums[0]
+	assert(result.Attribute(`nodiscard`) != nil && result.Name == `Result`)
+	assert(result.Enumerators[0].Attributes == nil && result.Enumerators[1].Attributes[0].Name == `deprecated`)
+	assert(result.Enumerators[1].Value == 2)
+	assert(file.Namespaces[0].Name == `old` && file.Namespaces[0].Attribute(`deprecated`) != nil)
+	assert(file.TypeAliases[0].Name == `Int` && file.TypeAliases[0].Attribute(`deprecated`) != nil)
+}
@@ -1,25 +1,26 @@
 package ymdCppHeaderParser
 
-import "unicode"
+// The classification follows the C locale so that EndOfFileChar and the bytes of utf-8 sequences are never
+// taken as part of an identifier or a number
 
 func isSpace(c byte) bool {
-	return unicode.IsSpace(rune(c))
+	return c == ' ' || ('\t' <= c && c <= '\r')
 }
 
 func isControl(c byte) bool {
-	return unicode.IsControl(rune(c))
+	return c < 0x20 || c == 0x7F
 }
 
 func isAlpha(c byte) bool {
-	return unicode.IsLower(rune(c)) || unicode.IsUpper(rune(c))
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
 }
 
 func isAlnum(c byte) bool {
-	return isAlpha(c) || unicode.IsNumber(rune(c))
+	return isAlpha(c) || isDigit(c)
 }
 
 func isDigit(c byte) bool {
-	return unicode.IsNumber(rune(c))
+	return '0' <= c && c <= '9'
 }
 
 func isXDigit(c byte) bool {
@@ -0,0 +1,255 @@
+package ymdCppHeaderParser
+
+import "strings"
+
+// Concept is a concept definition, e.g. template <class T> concept Hashable = requires (T a) { hash(a); };
+type Concept struct {
+	Declaration
+	Template
+	Expression       string      `json:",omitempty"` // Source of the constraint expression
+	ParsedExpression *Expression `json:",omitempty"` // nil if the constraint is no constant expression
+}
+
+// standardConcepts are the concepts of the standard library a constrained template parameter may use,
+// the concepts defined in the parsed file are known as well. Any other name which is no known type
+// is taken as a concept, see isUnknownTypeName.
+var standardConcepts = map[string]bool{
+	`same_as`: true, `derived_from`: true, `convertible_to`: true, `common_reference_with`: true,
+	`common_with`: true, `integral`: true, `signed_integral`: true, `unsigned_integral`: true,
+	`floating_point`: true, `assignable_from`: true, `swappable`: true, `swappable_with`: true,
+	`destructible`: true, `constructible_from`: true, `default_initializable`: true,
+	`move_constructible`: true, `copy_constructible`: true, `equality_comparable`: true,
+	`equality_comparable_with`: true, `totally_ordered`: true, `totally_ordered_with`: true,
+	`three_way_comparable`: true, `three_way_comparable_with`: true, `movable`: true, `copyable`: true,
+	`semiregular`: true, `regular`: true, `invocable`: true, `regular_invocable`: true, `predicate`: true,
+	`relation`: true, `equivalence_relation`: true, `strict_weak_order`: true,
+	`input_iterator`: true, `output_iterator`: true, `forward_iterator`: true,
+	`bidirectional_iterator`: true, `random_access_iterator`: true, `contiguous_iterator`: true,
+	`sentinel_for`: true, `sized_sentinel_for`: true, `indirectly_readable`: true,
+	`indirectly_writable`: true, `weakly_incrementable`: true, `incrementable`: true,
+	`input_or_output_iterator`: true, `sortable`: true, `mergeable`: true, `permutable`: true,
+	`ranges::range`: true, `ranges::sized_range`: true, `ranges::view`: true,
+	`ranges::input_range`: true, `ranges::output_range`: true, `ranges::forward_range`: true,
+	`ranges::bidirectional_range`: true, `ranges::random_access_range`: true,
+	`ranges::contiguous_range`: true, `ranges::common_range`: true, `ranges::viewable_range`: true,
+	`ranges::borrowed_range`: true,
+}
+
+// parseConcept parses a concept definition behind its template head
+func (this *Parser) parseConcept() bool {
+	const funcId = `g5u8ze2c `
+	var startToken Token
+	if !this.GetIdentifier(&startToken) || startToken.Mtoken != `concept` {
+		this.panicf(funcId, `Missing "concept" identifier`)
+	}
+	templateHead := this.takeTemplate()
+	var nameToken Token
+	if !this.GetIdentifier(&nameToken) {
+		this.panicf(funcId, `Missing concept name`)
+	}
+	this.parseAttributes()
+	this.requireSymbol(`=`)
+	concept := &Concept{
+		Declaration: this.newDeclaration(nameToken.Mtoken, &startToken),
+		Template:    templateHead,
+		Expression:  this.scanExpressionUntil(`;`),
+	}
+	concept.ParsedExpression, _ = ParseExpression(concept.Expression)
+	this.requireSymbol(`;`)
+	this.endDeclaration(&concept.Declaration)
+
+	if this.concepts == nil {
+		this.concepts = map[string]bool{}
+	}
+	this.concepts[concept.Name] = true
+	this.concepts[concept.QualifiedName()] = true
+	if this.isReported(&concept.Declaration) {
+		members := this.topScope().members
+		members.Concepts = append(members.Concepts, concept)
+	}
+	return true
+}
+
+// isConcept tells whether the type names a concept, e.g. the std::integral of template <std::integral T>
+func (this *Parser) isConcept(node *TypeNode) bool {
+	name, ok := constraintName(node)
+	if !ok {
+		return false
+	}
+	return this.concepts[name] || (strings.HasPrefix(name, `std::`) && standardConcepts[strings.TrimPrefix(name, `std::`)])
+}
+
+// constraintName returns the name of a type which may be a type constraint, e.g. Hashable or std::convertible_to
+// of std::convertible_to<int>. It fails for any other type, e.g. const T or T *.
+func constraintName(node *TypeNode) (string, bool) {
+	name := ``
+	switch node.NodeType {
+	case kLiteral:
+		name = node.LiteralName
+	case kTemplate:
+		name = node.TemplateName
+	default:
+		return ``, false
+	}
+	if node.IsConst || node.IsVolatile || node.Constraint != nil {
+		return ``, false
+	}
+	return strings.TrimPrefix(name, `::`), true
+}
+
+// declareType records a class, an enum or a type alias declared in the current scope,
+// a template parameter of such a type is no constrained type parameter, see isUnknownTypeName
+func (this *Parser) declareType(name string) {
+	if name == `` {
+		return
+	}
+	if this.types == nil {
+		this.types = map[string]bool{}
+	}
+	this.types[name] = true
+	this.types[joinQualifiedName(this.scopeName(), name)] = true
+}
+
+// isUnknownTypeName tells whether the type is a name which is neither a fundamental type nor a type declared so far
+// nor a type parameter, e.g. the Hashable of template <Hashable T> with a concept declared in another header.
+// The names of the standard library are known by standardConcepts.
+func (this *Parser) isUnknownTypeName(node *TypeNode) bool {
+	name, ok := constraintName(node)
+	if !ok || strings.HasPrefix(name, `std::`) || this.types[name] {
+		return false
+	}
+	if _, ok := lookupBuiltinType(name); ok || name == `void` || name == `auto` || strings.HasPrefix(name, `decltype(`) {
+		return false
+	}
+	for _, one := range this.templateParameters {
+		if one.Name == name && one.ParameterType != kNonTypeParameter {
+			return false
+		}
+	}
+	return true
+}
+
+// isTypeParameterName tells whether the cursor is at the name of a type parameter, e.g. the T of template <Hashable T>,
+// which ends the parameter or is followed by a default type rather than a default value
+func (this *Parser) isTypeParameterName() bool {
+	mark := Token{MstartPos: this.cursorPos, MstartLine: this.cursorLine}
+	defer this.UngetToken(&mark)
+	this.MatchSymbol(`...`)
+	var token Token
+	if !this.GetIdentifier(&token) || !this.GetToken(&token, false, true) {
+		return false
+	}
+	switch token.Mtoken {
+	case `,`, `>`:
+		return true
+	case `=`:
+		return this.GetToken(&token, false, true) && !isValueStart(&token)
+	}
+	return false
+}
+
+// constrainUsedTemplateParameters turns a non-type parameter whose name is used as the type of an argument or
+// of the return type into a constrained type parameter, e.g. the T of template <Hashable T> void g(T t);
+func constrainUsedTemplateParameters(template *Template, returnType *TypeNode, arguments []*Argument) {
+	for _, parameter := range template.TemplateParameters {
+		if parameter.ParameterType != kNonTypeParameter || parameter.Name == `` {
+			continue
+		}
+		if _, ok := constraintName(parameter.Type); !ok {
+			continue
+		}
+		isUsed := usesTypeName(returnType, parameter.Name)
+		for _, one := range arguments {
+			isUsed = isUsed || usesTypeName(one.Type, parameter.Name)
+		}
+		if isUsed {
+			parameter.ParameterType = kTypeParameter
+			parameter.Constraint = parameter.Type
+			parameter.Type = nil
+			parameter.DefaultValue = ``
+			parameter.ParsedDefaultValue = nil
+		}
+	}
+}
+
+// usesTypeName tells whether the type refers to the type name, e.g. const std::vector<T> & refers to T
+func usesTypeName(node *TypeNode, name string) bool {
+	if node == nil {
+		return false
+	}
+	switch node.NodeType {
+	case kLiteral:
+		return node.LiteralName == name
+	case kPointer:
+		return usesTypeName(node.PointerBase, name)
+	case kReference:
+		return usesTypeName(node.ReferenceBase, name)
+	case kLReference:
+		return usesTypeName(node.LReferenceBase, name)
+	case kArray:
+		return usesTypeName(node.ArrayBase, name)
+	case kMemberPointer:
+		return usesTypeName(node.MemberPointerBase, name)
+	case kTemplate:
+		for _, one := range node.TemplateArguments {
+			if usesTypeName(one, name) {
+				return true
+			}
+		}
+	case kFunction:
+		if usesTypeName(node.FunctionReturns, name) {
+			return true
+		}
+		for _, one := range node.FunctionArguments {
+			if usesTypeName(one.Type, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// scanRequiresClause returns the source of the constraint behind the requires keyword of a template
+// head or a function declaration, e.g. C<T> && (sizeof(T) > 4) of requires C<T> && (sizeof(T) > 4)
+func (this *Parser) scanRequiresClause() string {
+	const funcId = `l2c7dn4w `
+	startPos := this.cursorPos
+	endPos := startPos
+	for {
+		this.MatchSymbol(`!`)
+		var token Token
+		if !this.GetToken(&token, false, false) {
+			this.panicf(funcId, `Expected constraint`)
+		}
+		switch {
+		case token.Mtoken == `(` && token.MtokenType == kSymbol:
+			this.scanParenthesized()
+		case token.Mtoken == `requires`:
+			// requires (T a) { a + a; }
+			if this.MatchSymbol(`(`) {
+				this.scanParenthesized()
+			}
+			this.requireSymbol(`{`)
+			this.scanExpressionUntil(`}`)
+			this.requireSymbol(`}`)
+		case token.MtokenType == kConst:
+		case token.MtokenType == kIdentifier || token.Mtoken == `::`:
+			// A concept or a constant, e.g. std::integral<T> or std::is_integral_v<T>
+			this.UngetToken(&token)
+			this.parseTypeNodeDeclarator()
+			this.parseTemplateArgumentList()
+			for this.MatchSymbol(`::`) {
+				this.parseTypeNodeDeclarator()
+				this.parseTemplateArgumentList()
+			}
+		default:
+			this.UngetToken(&token)
+			this.panicf(funcId, `Expected constraint`)
+		}
+		endPos = this.cursorPos
+		if !this.MatchSymbol(`&&`) && !this.MatchSymbol(`||`) {
+			break
+		}
+	}
+	return strings.TrimSpace(string(this.input[startPos:endPos]))
+}
@@ -0,0 +1,111 @@
+package ymdCppHeaderParser
+
+import "testing"
+
+func TestParser_ParseConcepts(t *testing.T) {
+	p := NewParser([]byte(`
+namespace math {
+template <class T>
+concept Number = std::integral<T> || std::floating_point<T>;
+template <typename T>
+concept Hashable = requires (T a) { { std::hash<T>{}(a) } -> std::convertible_to<std::size_t>; };
+}
+
+enum class Mode { Fast };
+template <std::integral T, math::Number U, Other V, std::ranges::forward_range R, Mode M> struct Pair {};
+template <class T> requires math::Hashable<T> && (sizeof(T) > 4) class Table {};
+template <class T> requires requires (T x) { x + x; } T twice(T x);
+template <class T> void sort(T &values) requires std::sortable<T>;
+auto scale(std::integral auto value, const math::Number auto &factor) -> decltype(value * factor);
+template <std::same_as<int> auto N> void fixed();
+
+struct Value {
+	explicit(false) Value(int);
+	explicit(sizeof(long) >= 8) Value(long);
+	explicit Value(double);
+	consteval int compile() const;
+	auto operator<=>(const Value &) const = default;
+	static constinit int counter;
+};
+constinit int global = 1;
+`))
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+
+	concepts := file.Namespaces[0].Concepts
+	assert(len(concepts) == 2, len(concepts))
+	number := concepts[0]
+	assert(number.Name == `Number` && number.QualifiedName() == `math::Number` && number.IsTemplate)
+	assert(number.Expression == `std::integral<T> || std::floating_point<T>` && number.TemplateParameters[0].Name == `T`)
+	assert(concepts[1].Name == `Hashable` && concepts[1].ParsedExpression == nil)
+
+	parameters := file.Classes[0].TemplateParameters
+	assert(len(parameters) == 5, len(parameters))
+	assert(parameters[0].ParameterType == kTypeParameter && parameters[0].Constraint.LiteralName == `std::integral` && parameters[0].Name == `T`)
+	assert(parameters[1].ParameterType == kTypeParameter && parameters[1].Constraint.LiteralName == `math::Number`)
+	assert(parameters[2].ParameterType == kTypeParameter && parameters[2].Constraint.LiteralName == `Other`)
+	assert(parameters[3].ParameterType == kTypeParameter && parameters[3].Constraint.LiteralName == `std::ranges::forward_range`)
+	assert(parameters[4].ParameterType == kNonTypeParameter && parameters[4].Type.LiteralName == `Mode`)
+
+	table := file.Classes[1]
+	assert(table.Name == `Table` && table.RequiresClause == `math::Hashable<T> && (sizeof(T) > 4)`)
+
+	functions := file.Functions
+	assert(len(functions) == 4, len(functions))
+	assert(functions[0].Name == `twice` && functions[0].RequiresClause == `requires (T x) { x + x; }`)
+	assert(functions[1].Name == `sort` && functions[1].TrailingRequiresClause == `std::sortable<T>` && functions[1].RequiresClause == ``)
+	scale := functions[2]
+	assert(scale.Name == `scale` && len(scale.Arguments) == 2 && scale.Arguments[0].Name == `value`)
+	assert(scale.Arguments[0].Type.LiteralName == `auto` && scale.Arguments[0].Type.Constraint.LiteralName == `std::integral`)
+	factor := scale.Arguments[1].Type
+	assert(factor.NodeType == kReference && factor.ReferenceBase.IsConst && factor.ReferenceBase.Constraint.LiteralName == `math::Number`)
+	assert(scale.HasTrailingReturnType && scale.ReturnType.NodeType == kLiteral, marshalJson(scale.ReturnType))
+	assert(scale.ReturnType.LiteralName == `decltype(value * factor)`)
+	fixed := functions[3].TemplateParameters[0]
+	assert(fixed.ParameterType == kNonTypeParameter && fixed.Name == `N` && fixed.Type.Constraint.TemplateName == `std::same_as`)
+
+	value := file.Classes[2]
+	methods := value.Functions
+	assert(len(methods) == 5, len(methods))
+	assert(!methods[0].IsExplicit && methods[0].ExplicitExpression == `false` && methods[0].FunctionKind == kConstructor)
+	assert(methods[1].IsExplicit && methods[1].ExplicitExpression == `sizeof(long) >= 8`)
+	assert(methods[2].IsExplicit && methods[2].ExplicitExpression == ``)
+	assert(methods[3].Name == `compile` && methods[3].IsConsteval && !methods[3].IsConstExpr && methods[3].IsConst)
+	assert(methods[4].Name == `operator<=>` && methods[4].Operator == `<=>` && methods[4].IsDefault)
+	assert(value.Fields[0].Name == `counter` && value.Fields[0].IsStatic && value.Fields[0].IsConstinit)
+	assert(file.Fields[0].Name == `global` && file.Fields[0].IsConstinit && file.Fields[0].DefaultValue == `1`)
+}
+
+func TestParser_ParseUndeclaredConcepts(t *testing.T) {
+	p := NewParser([]byte(`
+#include "concepts.h"
+enum class Color { Red };
+template <Hashable T> void g(T t);
+template <class T, T Value, ns::Sized<4> U = int, Hashable... Ts, std::size_t N = 4, Color C = Color::Red, Other O = 1> struct S {};
+template <std::my_concept T> void h(const std::vector<T> &values);
+template <typename T> struct A { template <T V> void f(); };
+`))
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+
+	functions := file.Functions
+	assert(len(functions) == 2, len(functions))
+	g := functions[0].TemplateParameters[0]
+	assert(g.ParameterType == kTypeParameter && g.Name == `T` && g.Constraint.LiteralName == `Hashable` && g.Type == nil, marshalJson(g))
+	// A name of the standard library is taken as a constraint when it is used as a type
+	h := functions[1].TemplateParameters[0]
+	assert(h.ParameterType == kTypeParameter && h.Constraint.LiteralName == `std::my_concept`, marshalJson(h))
+
+	parameters := file.Classes[0].TemplateParameters
+	assert(len(parameters) == 7, len(parameters))
+	assert(parameters[1].ParameterType == kNonTypeParameter && parameters[1].Type.LiteralName == `T`)
+	assert(parameters[2].ParameterType == kTypeParameter && parameters[2].Constraint.TemplateName == `ns::Sized`)
+	assert(parameters[2].DefaultType.LiteralName == `int`)
+	assert(parameters[3].ParameterType == kTypeParameter && parameters[3].IsPack && parameters[3].Constraint.LiteralName == `Hashable`)
+	assert(parameters[4].ParameterType == kNonTypeParameter && parameters[4].DefaultValue == `4`)
+	assert(parameters[5].ParameterType == kNonTypeParameter && parameters[5].Type.LiteralName == `Color`)
+	assert(parameters[6].ParameterType == kNonTypeParameter && parameters[6].DefaultValue == `1`)
+
+	f := file.Classes[1].Functions[0].TemplateParameters[0]
+	assert(f.ParameterType == kNonTypeParameter && f.Type.LiteralName == `T`)
+}
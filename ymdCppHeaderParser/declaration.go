@@ -0,0 +1,214 @@
+package ymdCppHeaderParser
+
+// Declaration is the information shared by every entity of the parsed model
+type Declaration struct {
+	Name   string            `json:",omitempty"`
+	Scope  string            `json:",omitempty"` // Fully-qualified enclosing scope, e.g. ns1::ns2::ClassType
+	Access AccessControlType `json:",omitempty"`
+	Line   int               `json:",omitempty"`
+	Macro  string            `json:",omitempty"` // Annotation macro in front of the declaration, see ParserOptions
+	Meta   *MetaValue        `json:",omitempty"` // Arguments of the annotation macro
+
+	Comment         string       `json:",omitempty"` // Comment on the lines right in front of the declaration
+	TrailingComment string       `json:",omitempty"` // Comment behind the declaration on its last line
+	Doc             *DocComment  `json:",omitempty"` // Structure of the leading comment
+	Attributes      []*Attribute `json:",omitempty"`
+
+	// Scope without inline namespaces, only set inside an inline namespace, e.g. std for std::__1
+	VisibleScope string `json:",omitempty"`
+
+	startPos       int  // keeps the source order of declarations of different kinds
+	isInlineScoped bool // VisibleScope is set
+}
+
+func (this *Declaration) QualifiedName() string {
+	return joinQualifiedName(this.Scope, this.Name)
+}
+
+// VisibleQualifiedName is the qualified name without inline namespaces, e.g. std::string for std::__1::string
+func (this *Declaration) VisibleQualifiedName() string {
+	if !this.isInlineScoped {
+		return this.QualifiedName()
+	}
+	return joinQualifiedName(this.VisibleScope, this.Name)
+}
+
+func joinQualifiedName(scope string, name string) string {
+	if scope == `` {
+		return name
+	}
+	return scope + `::` + name
+}
+
+// Members holds everything declared directly inside a file, a namespace or a class.
+// For classes the functions are the methods and the fields are the data members.
+type Members struct {
+	Namespaces       []*Namespace      `json:",omitempty"`
+	NamespaceAliases []*NamespaceAlias `json:",omitempty"`
+	Classes          []*Class          `json:",omitempty"`
+	Enums            []*Enum           `json:",omitempty"`
+	Functions        []*Function       `json:",omitempty"`
+	Fields           []*Field          `json:",omitempty"`
+
+	TypeAliases       []*TypeAlias        `json:",omitempty"`
+	UsingDirectives   []*UsingDirective   `json:",omitempty"`
+	UsingDeclarations []*UsingDeclaration `json:",omitempty"`
+	StaticAsserts     []*StaticAssert     `json:",omitempty"`
+	Concepts          []*Concept          `json:",omitempty"`
+}
+
+type File struct {
+	Includes []*Include `json:",omitempty"`
+	Members
+}
+
+type Include struct {
+	Path     string `json:",omitempty"`
+	IsSystem bool   `json:",omitempty"` // #include <...>
+	Line     int    `json:",omitempty"`
+
+	startPos int
+}
+
+// Namespace is a namespace definition, the name of an anonymous namespace is empty.
+// namespace a::b { ... } is modelled as two nested namespaces.
+type Namespace struct {
+	Declaration
+	IsInline bool `json:",omitempty"`
+	Members
+}
+
+// NamespaceAlias is a namespace alias definition, e.g. namespace fs = std::filesystem;
+type NamespaceAlias struct {
+	Declaration
+	Target string `json:",omitempty"` // As written, e.g. std::filesystem
+}
+
+type BaseClass struct {
+	Access    AccessControlType `json:",omitempty"`
+	Name      string            `json:",omitempty"` // As written, e.g. ns::Base<T>
+	Type      *TypeNode         `json:",omitempty"`
+	IsVirtual bool              `json:",omitempty"` // virtual inheritance
+	IsPack    bool              `json:",omitempty"` // pack expansion, e.g. Bases... of template <class... Bases>
+}
+
+type Class struct {
+	Declaration
+	Template
+	IsStruct    bool         `json:",omitempty"`
+	IsUnion     bool         `json:",omitempty"`
+	IsAnonymous bool         `json:",omitempty"` // Unnamed without declarators, its fields are also listed in the enclosing scope
+	IsFinal     bool         `json:",omitempty"`
+	ExportMacro string       `json:",omitempty"` // e.g. MYLIB_API of class MYLIB_API Foo, see ParserOptions.ExportMacros
+	Bases       []*BaseClass `json:",omitempty"`
+	Friends     []*Friend    `json:",omitempty"`
+	Members
+}
+
+// Friend is a friend declaration of a class, e.g. friend class Helper; or friend bool operator==(A, A);
+type Friend struct {
+	Template           // template <class T> friend class Box;
+	Name     string    `json:",omitempty"` // As written, e.g. ns::Helper, or the name of the function
+	Type     *TypeNode `json:",omitempty"` // The befriended class
+	Function *Function `json:",omitempty"` // The befriended function, it is not listed in the functions of the class
+	Line     int       `json:",omitempty"`
+}
+
+// StaticAssert is a static assertion, e.g. static_assert(sizeof(Foo) == 8, "Foo changed");
+type StaticAssert struct {
+	Expression       string      `json:",omitempty"` // Source of the condition
+	ParsedExpression *Expression `json:",omitempty"` // nil if the condition is no constant expression
+	Message          string      `json:",omitempty"` // Without the quotes, empty if there is none
+	Scope            string      `json:",omitempty"`
+	Line             int         `json:",omitempty"`
+}
+
+// Enum is an enum declaration, an anonymous enum has an empty Name
+type Enum struct {
+	Declaration
+	IsClass     bool          `json:",omitempty"` // enum class
+	BaseType    string        `json:",omitempty"` // Underlying type, e.g. std::uint8_t
+	Enumerators []*Enumerator `json:",omitempty"`
+}
+
+type Enumerator struct {
+	Name             string      `json:",omitempty"`
+	Expression       string      `json:",omitempty"` // Source of the explicit value, if any
+	ParsedExpression *Expression `json:",omitempty"` // nil if the source is no constant expression
+	Value            int64       `json:",omitempty"`
+	HasValue         bool        `json:",omitempty"` // false if the expression can not be evaluated, e.g. it uses a macro
+	Line             int         `json:",omitempty"`
+
+	Comment         string       `json:",omitempty"`
+	TrailingComment string       `json:",omitempty"`
+	Attributes      []*Attribute `json:",omitempty"`
+}
+
+type FunctionKind string
+
+const (
+	kOrdinaryFunction   FunctionKind = `kOrdinaryFunction`
+	kConstructor        FunctionKind = `kConstructor`
+	kDestructor         FunctionKind = `kDestructor`
+	kConversionOperator FunctionKind = `kConversionOperator` // operator bool(), ReturnType is the target type
+	kOperator           FunctionKind = `kOperator`           // operator==, operator() or operator new
+)
+
+type Function struct {
+	Declaration
+	Template
+	FunctionKind FunctionKind `json:",omitempty"`
+	Operator     string       `json:",omitempty"` // Symbol of an overloaded operator, e.g. ==, (), new[] or ""_km
+	ReturnType   *TypeNode    `json:",omitempty"` // nil for constructors and destructors
+	Arguments    []*Argument  `json:",omitempty"`
+
+	IsVirtual   bool `json:",omitempty"`
+	IsInline    bool `json:",omitempty"`
+	IsConstExpr bool `json:",omitempty"`
+	IsStatic    bool `json:",omitempty"`
+	IsConst     bool `json:",omitempty"`
+	IsPure      bool `json:",omitempty"`
+	IsExplicit  bool `json:",omitempty"`
+	IsFriend    bool `json:",omitempty"`
+	IsConsteval bool `json:",omitempty"`
+	IsNodiscard bool `json:",omitempty"` // [[nodiscard]]
+	IsDefault   bool `json:",omitempty"` // = default
+	IsDeleted   bool `json:",omitempty"` // = delete
+
+	IsVolatile   bool   `json:",omitempty"`
+	RefQualifier string `json:",omitempty"` // & or && behind the parameter list
+	IsOverride   bool   `json:",omitempty"`
+	IsFinal      bool   `json:",omitempty"`
+
+	IsNoexcept            bool        `json:",omitempty"` // noexcept, noexcept(expression) unless it is false, or throw()
+	NoexceptExpression    string      `json:",omitempty"` // Source of the expression of noexcept(expression)
+	HasThrowSpecification bool        `json:",omitempty"` // throw(...)
+	ThrowTypes            []*TypeNode `json:",omitempty"`
+
+	HasTrailingReturnType bool `json:",omitempty"` // auto f() -> int, ReturnType is the trailing type
+
+	ExplicitExpression     string `json:",omitempty"` // Source of the expression of explicit(expression)
+	TrailingRequiresClause string `json:",omitempty"` // Constraint of void f(T) requires C<T>;
+
+	Linkage string `json:",omitempty"` // Language of the enclosing extern "C", empty for C++ and extern "C++"
+}
+
+type Field struct {
+	Declaration
+	Type *TypeNode `json:",omitempty"`
+
+	IsStatic  bool `json:",omitempty"`
+	IsMutable bool `json:",omitempty"`
+	IsExtern  bool `json:",omitempty"` // extern int count; declares a variable defined elsewhere
+
+	IsConstinit bool `json:",omitempty"`
+
+	BitWidth       string      `json:",omitempty"` // Source of the width of a bit-field, e.g. 3 of unsigned flags : 3;
+	ParsedBitWidth *Expression `json:",omitempty"`
+	// Source of the default member initializer or of the initializer of a variable, e.g. 0 of int count = 0;
+	// A brace initializer keeps its braces, e.g. {"x"} of std::string name{"x"};
+	DefaultValue       string      `json:",omitempty"`
+	ParsedDefaultValue *Expression `json:",omitempty"` // nil if the initializer is no constant expression
+
+	Linkage string `json:",omitempty"` // Language of the enclosing extern "C", empty for C++ and extern "C++"
+}
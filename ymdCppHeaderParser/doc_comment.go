@@ -0,0 +1,202 @@
+package ymdCppHeaderParser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DocComment is the doxygen / javadoc structure of a documentation comment.
+// Commands are recognized with both the @ and the \ prefix.
+type DocComment struct {
+	Brief        string      `json:",omitempty"` // @brief, otherwise the first paragraph
+	Description  string      `json:",omitempty"` // The remaining text, paragraphs are separated by an empty line
+	Params       []*DocParam `json:",omitempty"`
+	Return       string      `json:",omitempty"`
+	IsDeprecated bool        `json:",omitempty"`
+	Deprecated   string      `json:",omitempty"` // Text of @deprecated
+	See          []string    `json:",omitempty"`
+	Code         []string    `json:",omitempty"` // Content of the @code ... @endcode blocks
+	Warnings     []string    `json:",omitempty"` // Mismatches between the documented and the declared parameters
+}
+
+type DocParam struct {
+	Name        string `json:",omitempty"`
+	Direction   string `json:",omitempty"` // in, out or in,out of @param[in,out]
+	Description string `json:",omitempty"`
+}
+
+// ParseDocComment parses the text of a comment as returned by the parser, nil for an empty comment
+func ParseDocComment(comment string) *DocComment {
+	if strings.TrimSpace(comment) == `` {
+		return nil
+	}
+	doc := &DocComment{}
+	var paragraphs []string
+	var target *string // text of a command which continues on the next line
+	var code []string
+	inCode := false
+	hasBrief := false
+
+	appendText := func(text *string, line string) {
+		if *text == `` {
+			*text = line
+		} else {
+			*text += ` ` + line
+		}
+	}
+
+	for _, line := range strings.Split(comment, "\n") {
+		if inCode {
+			if command, _ := docCommand(strings.TrimSpace(line)); command == `endcode` {
+				doc.Code = append(doc.Code, strings.Join(code, "\n"))
+				code = nil
+				inCode = false
+			} else {
+				code = append(code, line)
+			}
+			continue
+		}
+		line = strings.TrimSpace(line)
+		if line == `` {
+			// Ends the paragraph and any command
+			target = nil
+			if len(paragraphs) != 0 && paragraphs[len(paragraphs)-1] != `` {
+				paragraphs = append(paragraphs, ``)
+			}
+			continue
+		}
+		command, rest := docCommand(line)
+		switch command {
+		case ``:
+			if target != nil {
+				appendText(target, line)
+			} else if len(paragraphs) == 0 || paragraphs[len(paragraphs)-1] == `` {
+				paragraphs = append(paragraphs, line)
+			} else {
+				appendText(&paragraphs[len(paragraphs)-1], line)
+			}
+			continue
+		case `brief`, `short`:
+			hasBrief = true
+			doc.Brief = rest
+			target = &doc.Brief
+		case `param`, `tparam`:
+			param := &DocParam{}
+			if strings.HasPrefix(rest, `[`) {
+				if end := strings.IndexByte(rest, ']'); end >= 0 {
+					param.Direction = strings.ReplaceAll(rest[1:end], ` `, ``)
+					rest = strings.TrimSpace(rest[end+1:])
+				}
+			}
+			param.Name, param.Description = docWord(rest)
+			if command == `param` {
+				doc.Params = append(doc.Params, param)
+			}
+			target = &param.Description
+		case `return`, `returns`, `result`:
+			doc.Return = rest
+			target = &doc.Return
+		case `deprecated`:
+			doc.IsDeprecated = true
+			doc.Deprecated = rest
+			target = &doc.Deprecated
+		case `see`, `sa`:
+			doc.See = append(doc.See, rest)
+			target = &doc.See[len(doc.See)-1]
+		case `code`:
+			inCode = true
+			target = nil
+		default:
+			// Unknown commands stay part of the text
+			if target != nil {
+				appendText(target, line)
+			} else {
+				paragraphs = append(paragraphs, line)
+			}
+			continue
+		}
+		// A command ends the current paragraph
+		if len(paragraphs) != 0 && paragraphs[len(paragraphs)-1] != `` {
+			paragraphs = append(paragraphs, ``)
+		}
+	}
+	if inCode {
+		// Unterminated code block
+		doc.Code = append(doc.Code, strings.Join(code, "\n"))
+	}
+
+	for len(paragraphs) != 0 && paragraphs[len(paragraphs)-1] == `` {
+		paragraphs = paragraphs[:len(paragraphs)-1]
+	}
+	if !hasBrief && len(paragraphs) != 0 {
+		doc.Brief = paragraphs[0]
+		paragraphs = paragraphs[1:]
+		if len(paragraphs) != 0 {
+			paragraphs = paragraphs[1:]
+		}
+	}
+	doc.Description = strings.Join(paragraphs, "\n")
+	return doc
+}
+
+// docCommand splits a line starting with a command into the command name and the remaining text
+func docCommand(line string) (command string, rest string) {
+	if len(line) < 2 || (line[0] != '@' && line[0] != '\\') || !isAlpha(line[1]) {
+		return ``, line
+	}
+	end := 1
+	for end < len(line) && isAlnum(line[end]) {
+		end++
+	}
+	command = line[1:end]
+	rest = line[end:]
+	if command == `code` && strings.HasPrefix(rest, `{`) {
+		// @code{.cpp}
+		if idx := strings.IndexByte(rest, '}'); idx >= 0 {
+			rest = rest[idx+1:]
+		}
+	}
+	return command, strings.TrimSpace(rest)
+}
+
+// docWord splits the first word from the text
+func docWord(text string) (word string, rest string) {
+	idx := strings.IndexAny(text, " \t")
+	if idx < 0 {
+		return text, ``
+	}
+	return text[:idx], strings.TrimSpace(text[idx:])
+}
+
+// Param returns the documentation of the parameter with the given name
+func (this *DocComment) Param(name string) *DocParam {
+	for _, one := range this.Params {
+		if one.Name == name {
+			return one
+		}
+	}
+	return nil
+}
+
+// matchArguments copies the parameter descriptions to the arguments and records the
+// parameters documented but not declared. Undocumented arguments are only reported once
+// any parameter is documented.
+func (this *DocComment) matchArguments(arguments []*Argument) {
+	declared := map[string]bool{}
+	for _, one := range arguments {
+		if one.Name == `` {
+			continue
+		}
+		declared[one.Name] = true
+		if param := this.Param(one.Name); param != nil {
+			one.Comment = param.Description
+		} else if len(this.Params) != 0 {
+			this.Warnings = append(this.Warnings, fmt.Sprintf(`parameter "%v" is not documented`, one.Name))
+		}
+	}
+	for _, one := range this.Params {
+		if !declared[one.Name] {
+			this.Warnings = append(this.Warnings, fmt.Sprintf(`parameter "%v" is documented but not declared`, one.Name))
+		}
+	}
+}
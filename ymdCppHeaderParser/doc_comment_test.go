@@ -0,0 +1,67 @@
+package ymdCppHeaderParser
+
+import "testing"
+
+func TestParseDocComment(t *testing.T) {
+	doc := ParseDocComment(`Computes the sum.
+
+Long description
+spanning lines.
+@param[in] a the first
+       value
+\param b second value
+@return the sum
+@deprecated use add2
+@see add2
+@code{.cpp}
+  int c = add(1, 2);
+@endcode`)
+	assert(doc.Brief == `Computes the sum.`, doc.Brief)
+	assert(doc.Description == `Long description spanning lines.`, doc.Description)
+	assert(len(doc.Params) == 2)
+	assert(doc.Params[0].Name == `a` && doc.Params[0].Direction == `in` && doc.Params[0].Description == `the first value`)
+	assert(doc.Param(`b`).Description == `second value`)
+	assert(doc.Return == `the sum`)
+	assert(doc.IsDeprecated && doc.Deprecated == `use add2`)
+	assert(len(doc.See) == 1 && doc.See[0] == `add2`)
+	assert(len(doc.Code) == 1 && doc.Code[0] == `  int c = add(1, 2);`, doc.Code)
+
+	doc = ParseDocComment("@brief Short\nmore brief\n\nDetails")
+	assert(doc.Brief == `Short more brief` && doc.Description == `Details`, doc.Brief, doc.Description)
+
+	assert(ParseDocComment(" \n") == nil)
+}
+
+func TestParser_ParseAllDocComment(t *testing.T) {
+	p := NewParser([]byte(`
+/**
+ * @brief Adds.
+ * @param a first
+ * @param c unknown
+ */
+int add(int a, int b);
+
+/// Subtracts.
+/// \param a first
+/// \param b second
+int sub(int a, int b);
+
+/// No parameters documented.
+int mul(int a, int b);
+`))
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	add := file.Functions[0]
+	assert(add.Doc.Brief == `Adds.`)
+	assert(add.Arguments[0].Comment == `first` && add.Arguments[1].Comment == ``)
+	assert(len(add.Doc.Warnings) == 2, add.Doc.Warnings)
+	assert(add.Doc.Warnings[0] == `parameter "b" is not documented`, add.Doc.Warnings[0])
+	assert(add.Doc.Warnings[1] == `parameter "c" is documented but not declared`, add.Doc.Warnings[1])
+
+	sub := file.Functions[1]
+	assert(sub.Doc.Brief == `Subtracts.` && len(sub.Doc.Warnings) == 0, sub.Doc.Warnings)
+	assert(sub.Arguments[1].Comment == `second`)
+
+	mul := file.Functions[2]
+	assert(mul.Doc.Brief == `No parameters documented.` && len(mul.Doc.Warnings) == 0)
+}
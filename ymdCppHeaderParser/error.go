@@ -0,0 +1,52 @@
+package ymdCppHeaderParser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseError describes why the input could not be parsed, use errors.As to get it from the returned error
+type ParseError struct {
+	File    string `json:",omitempty"`
+	Line    int    `json:",omitempty"`
+	Column  int    `json:",omitempty"`
+	Code    string `json:",omitempty"` // funcId of the function which detected the error
+	Message string `json:",omitempty"`
+}
+
+func (this *ParseError) Error() string {
+	position := fmt.Sprintf("%v:%v", this.Line, this.Column)
+	if this.File != `` {
+		position = this.File + `:` + position
+	}
+	return fmt.Sprintf("%v: %v (%v)", position, this.Message, this.Code)
+}
+
+// Diagnostic is an error the parser recovered from by skipping the statement, see ParserOptions.Recover
+type Diagnostic struct {
+	*ParseError
+	SkippedStartLine   int `json:",omitempty"`
+	SkippedStartColumn int `json:",omitempty"`
+	SkippedEndLine     int `json:",omitempty"`
+	SkippedEndColumn   int `json:",omitempty"`
+}
+
+// Diagnostics is the error returned by ParseAll in recovery mode
+type Diagnostics []*Diagnostic
+
+func (this Diagnostics) Error() string {
+	var lines []string
+	for _, one := range this {
+		lines = append(lines, one.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Unwrap lets errors.As find the first *ParseError
+func (this Diagnostics) Unwrap() []error {
+	var list []error
+	for _, one := range this {
+		list = append(list, one.ParseError)
+	}
+	return list
+}
@@ -0,0 +1,562 @@
+package ymdCppHeaderParser
+
+import "strings"
+
+type ExpressionType string
+
+const (
+	kExpressionLiteral     ExpressionType = `kExpressionLiteral`
+	kExpressionIdentifier  ExpressionType = `kExpressionIdentifier`
+	kExpressionUnary       ExpressionType = `kExpressionUnary`
+	kExpressionBinary      ExpressionType = `kExpressionBinary`
+	kExpressionConditional ExpressionType = `kExpressionConditional`
+	kExpressionCast        ExpressionType = `kExpressionCast`
+	kExpressionSizeof      ExpressionType = `kExpressionSizeof`
+	kExpressionCall        ExpressionType = `kExpressionCall`
+)
+
+// Expression is a node of the syntax tree of a constant expression
+type Expression struct {
+	ExpressionType ExpressionType `json:",omitempty"`
+
+	// LiteralExpression
+	ConstType    ConstType `json:",omitempty"`
+	StringConst  string    `json:",omitempty"`
+	BoolConst    bool      `json:",omitempty"`
+	Int64Const   int64     `json:",omitempty"`
+	Float64Const float64   `json:",omitempty"`
+
+	// IdentifierExpression, e.g. ns::Color::Red, and the function of a CallExpression
+	Identifier string `json:",omitempty"`
+
+	// UnaryExpression, BinaryExpression, and the operand of CastExpression and SizeofExpression
+	Operator string      `json:",omitempty"`
+	Operand  *Expression `json:",omitempty"`
+	Left     *Expression `json:",omitempty"`
+	Right    *Expression `json:",omitempty"`
+
+	// ConditionalExpression, Condition ? Then : Else
+	Condition *Expression `json:",omitempty"`
+	Then      *Expression `json:",omitempty"`
+	Else      *Expression `json:",omitempty"`
+
+	// CastExpression, e.g. (int)x, int(x) or static_cast<int>(x), and SizeofExpression of a type
+	Type *TypeNode `json:",omitempty"`
+
+	// CallExpression
+	Arguments []*Expression `json:",omitempty"`
+}
+
+type builtinType struct {
+	size     int64
+	signed   bool
+	integral bool
+}
+
+// builtinTypes are the fundamental types on the LP64 data model
+var builtinTypes = map[string]builtinType{
+	`bool`:               {1, false, true},
+	`char`:               {1, true, true},
+	`signed char`:        {1, true, true},
+	`unsigned char`:      {1, false, true},
+	`char8_t`:            {1, false, true},
+	`char16_t`:           {2, false, true},
+	`char32_t`:           {4, false, true},
+	`wchar_t`:            {4, true, true},
+	`short`:              {2, true, true},
+	`unsigned short`:     {2, false, true},
+	`int`:                {4, true, true},
+	`unsigned int`:       {4, false, true},
+	`long`:               {8, true, true},
+	`unsigned long`:      {8, false, true},
+	`long long`:          {8, true, true},
+	`unsigned long long`: {8, false, true},
+	`float`:              {4, true, false},
+	`double`:             {8, true, false},
+	`long double`:        {16, true, false},
+	`__int128`:           {16, true, true},
+	`unsigned __int128`:  {16, false, true},
+	`int8_t`:             {1, true, true},
+	`uint8_t`:            {1, false, true},
+	`int16_t`:            {2, true, true},
+	`uint16_t`:           {2, false, true},
+	`int32_t`:            {4, true, true},
+	`uint32_t`:           {4, false, true},
+	`int64_t`:            {8, true, true},
+	`uint64_t`:           {8, false, true},
+	`size_t`:             {8, false, true},
+	`ptrdiff_t`:          {8, true, true},
+	`intptr_t`:           {8, true, true},
+	`uintptr_t`:          {8, false, true},
+}
+
+// builtinTypeWords are the keywords a fundamental type is written with
+var builtinTypeWords = map[string]bool{
+	`void`: true, `bool`: true, `char`: true, `wchar_t`: true, `char8_t`: true, `char16_t`: true, `char32_t`: true,
+	`short`: true, `int`: true, `long`: true, `signed`: true, `unsigned`: true, `float`: true, `double`: true,
+	`__int128`: true,
+}
+
+// lookupBuiltinType finds the fundamental type written as name, e.g. "long unsigned int" or "std::uint8_t"
+func lookupBuiltinType(name string) (builtinType, bool) {
+	name = strings.TrimPrefix(name, `std::`)
+	if one, ok := builtinTypes[name]; ok {
+		return one, true
+	}
+	normalized, ok := normalizeBuiltinType(strings.Fields(name))
+	if !ok {
+		return builtinType{}, false
+	}
+	one, ok := builtinTypes[normalized]
+	return one, ok
+}
+
+// normalizeBuiltinType returns the spelling of a fundamental type written with the given keywords in
+// any order, e.g. unsigned long long for long unsigned int long. It tells whether they form a type.
+func normalizeBuiltinType(words []string) (string, bool) {
+	counts := map[string]int{}
+	for _, word := range words {
+		counts[word]++
+	}
+	if len(words) == 0 || counts[`signed`]+counts[`unsigned`] > 1 || counts[`int`] > 1 || counts[`long`] > 2 {
+		return ``, false
+	}
+	// Bring the keywords into the order of the table, "int" is implied by the size and sign keywords
+	base := ``
+	for _, word := range words {
+		switch word {
+		case `signed`, `unsigned`, `int`, `long`, `short`:
+		default:
+			if base != `` {
+				return ``, false
+			}
+			base = word
+		}
+	}
+	size := strings.TrimSpace(strings.Repeat(`long `, counts[`long`]) + strings.Repeat(`short `, counts[`short`]))
+	var normalized string
+	switch {
+	case base == ``:
+		normalized = size
+		if normalized == `` {
+			normalized = `int`
+		}
+	case size == `` || (size == `long` && base == `double`):
+		normalized = strings.TrimSpace(size + ` ` + base)
+	default:
+		return ``, false
+	}
+	// Sign keywords only apply to the integer types, char and __int128
+	hasSign := counts[`signed`]+counts[`unsigned`] > 0
+	if base != `` && (counts[`int`] > 0 || (hasSign && base != `char` && base != `__int128`)) {
+		return ``, false
+	}
+	if counts[`unsigned`] > 0 || (counts[`signed`] > 0 && normalized == `char`) {
+		prefix := `signed `
+		if counts[`unsigned`] > 0 {
+			prefix = `unsigned `
+		}
+		normalized = prefix + normalized
+	}
+	if normalized == `void` {
+		return normalized, len(words) == 1
+	}
+	_, ok := builtinTypes[normalized]
+	return normalized, ok
+}
+
+// binaryPrecedence is the precedence of the binary operators, a higher value binds stronger
+var binaryPrecedence = map[string]int{
+	`*`: 10, `/`: 10, `%`: 10,
+	`+`: 9, `-`: 9,
+	`<<`: 8, `>>`: 8,
+	`<`: 7, `<=`: 7, `>`: 7, `>=`: 7,
+	`==`: 6, `!=`: 6,
+	`&`:  5,
+	`^`:  4,
+	`|`:  3,
+	`&&`: 2,
+	`||`: 1,
+}
+
+// ParseExpression parses the source of a constant expression, e.g. the value of an enumerator
+func ParseExpression(source string) (expression *Expression, err error) {
+	p := NewParser([]byte(source))
+	defer p.catchParseError(&err)
+	parsed := p.parseExpression()
+	var token Token
+	if p.GetToken(&token, false, false) {
+		p.UngetToken(&token)
+		p.panicf(`m2c8v5qe `, `Unexpected %v after the expression`, token.Mtoken)
+	}
+	return parsed, nil
+}
+
+func (this *Parser) parseExpression() *Expression {
+	const funcId = `c5e1w9hz `
+	defer this.enter(funcId)()
+	condition := this.parseBinaryExpression(1)
+	if !this.MatchSymbol(`?`) {
+		return condition
+	}
+	expression := &Expression{
+		ExpressionType: kExpressionConditional,
+		Condition:      condition,
+		Then:           this.parseExpression(),
+	}
+	this.requireSymbol(`:`)
+	expression.Else = this.parseExpression()
+	return expression
+}
+
+// parseBinaryExpression parses the operators binding at least as strong as minPrecedence
+func (this *Parser) parseBinaryExpression(minPrecedence int) *Expression {
+	const funcId = `x7k0dq3n `
+	defer this.enter(funcId)()
+	left := this.parseUnaryExpression()
+	for {
+		var token Token
+		if !this.GetToken(&token, false, false) {
+			return left
+		}
+		operator := token.Mtoken
+		if token.MtokenType == kConst && token.MconstType != kString && (operator[0] == '-' || operator[0] == '+') {
+			// The tokenizer reads the sign into the number, a-1 is a minus followed by 1
+			operator = operator[:1]
+		} else if token.MtokenType != kSymbol {
+			this.UngetToken(&token)
+			return left
+		}
+		precedence, ok := binaryPrecedence[operator]
+		if !ok || precedence < minPrecedence {
+			this.UngetToken(&token)
+			return left
+		}
+		// Continue behind the operator
+		this.cursorPos = token.MstartPos + len(operator)
+		this.cursorLine = token.MstartLine
+		left = &Expression{
+			ExpressionType: kExpressionBinary,
+			Operator:       operator,
+			Left:           left,
+			Right:          this.parseBinaryExpression(precedence + 1),
+		}
+	}
+}
+
+func (this *Parser) parseUnaryExpression() *Expression {
+	const funcId = `q1u9fy4w `
+	defer this.enter(funcId)()
+	var token Token
+	if !this.GetToken(&token, false, false) {
+		this.panicf(funcId, `Expected an expression`)
+	}
+	switch {
+	case token.MtokenType == kConst:
+		return &Expression{
+			ExpressionType: kExpressionLiteral,
+			ConstType:      token.MconstType,
+			StringConst:    token.MstringConst,
+			BoolConst:      token.MboolConst,
+			Int64Const:     token.Mint64Const,
+			Float64Const:   token.Mfloat64Const,
+		}
+	case token.Mtoken == `sizeof`:
+		expression := &Expression{ExpressionType: kExpressionSizeof}
+		if this.MatchSymbol(`(`) {
+			if expression.Type = this.parseBuiltinType(); expression.Type == nil {
+				expression.Operand = this.parseExpression()
+			}
+			this.requireSymbol(`)`)
+			return expression
+		}
+		expression.Operand = this.parseUnaryExpression()
+		return expression
+	case token.Mtoken == `static_cast` || token.Mtoken == `const_cast` || token.Mtoken == `reinterpret_cast`:
+		this.requireSymbol(`<`)
+		expression := &Expression{
+			ExpressionType: kExpressionCast,
+			Operator:       token.Mtoken,
+		}
+		if expression.Type = this.parseBuiltinType(); expression.Type == nil {
+			expression.Type = this.parseTypeNode()
+		}
+		this.requireSymbol(`>`)
+		this.requireSymbol(`(`)
+		expression.Operand = this.parseExpression()
+		this.requireSymbol(`)`)
+		return expression
+	case token.MtokenType == kIdentifier || token.Mtoken == `::`:
+		this.UngetToken(&token)
+		if typeNode := this.parseBuiltinType(); typeNode != nil {
+			// Functional cast, e.g. int(x)
+			this.requireSymbol(`(`)
+			expression := &Expression{
+				ExpressionType: kExpressionCast,
+				Type:           typeNode,
+				Operand:        this.parseExpression(),
+			}
+			this.requireSymbol(`)`)
+			return expression
+		}
+		identifier := this.parseTypeNodeDeclarator()
+		if !this.MatchSymbol(`(`) {
+			return &Expression{
+				ExpressionType: kExpressionIdentifier,
+				Identifier:     identifier,
+			}
+		}
+		expression := &Expression{
+			ExpressionType: kExpressionCall,
+			Identifier:     identifier,
+		}
+		if !this.MatchSymbol(`)`) {
+			for {
+				expression.Arguments = append(expression.Arguments, this.parseExpression())
+				if !this.MatchSymbol(`,`) {
+					break
+				}
+			}
+			this.requireSymbol(`)`)
+		}
+		return expression
+	case token.Mtoken == `(`:
+		if typeNode := this.parseBuiltinType(); typeNode != nil {
+			// C style cast, e.g. (unsigned char)x
+			this.requireSymbol(`)`)
+			return &Expression{
+				ExpressionType: kExpressionCast,
+				Type:           typeNode,
+				Operand:        this.parseUnaryExpression(),
+			}
+		}
+		expression := this.parseExpression()
+		this.requireSymbol(`)`)
+		return expression
+	case token.Mtoken == `-` || token.Mtoken == `+` || token.Mtoken == `~` || token.Mtoken == `!`:
+		return &Expression{
+			ExpressionType: kExpressionUnary,
+			Operator:       token.Mtoken,
+			Operand:        this.parseUnaryExpression(),
+		}
+	}
+	this.UngetToken(&token)
+	this.panicf(funcId, `Unexpected %v in expression`, token.Mtoken)
+	return nil
+}
+
+// parseBuiltinType parses a fundamental type such as "const unsigned long *" or "std::uint8_t".
+// If the next tokens are no such type the cursor is left unchanged and nil is returned.
+func (this *Parser) parseBuiltinType() *TypeNode {
+	var startToken Token
+	if !this.GetToken(&startToken, false, false) {
+		return nil
+	}
+	this.UngetToken(&startToken)
+
+	var words []string
+	isConst := false
+	isStd := false
+	for {
+		var token Token
+		if !this.GetIdentifier(&token) {
+			break
+		}
+		if token.Mtoken == `const` {
+			isConst = true
+			continue
+		}
+		if token.Mtoken == `std` && len(words) == 0 && !isStd && this.MatchSymbol(`::`) {
+			isStd = true
+			continue
+		}
+		if _, ok := builtinTypes[token.Mtoken]; (ok || builtinTypeWords[token.Mtoken]) && !(isStd && len(words) != 0) {
+			words = append(words, token.Mtoken)
+			continue
+		}
+		this.UngetToken(&token)
+		break
+	}
+	name := strings.Join(words, ` `)
+	if _, ok := lookupBuiltinType(name); len(words) == 0 || (!ok && name != `void`) {
+		this.UngetToken(&startToken)
+		return nil
+	}
+	if isStd {
+		name = `std::` + name
+	}
+	node := NewLiteralNode(name)
+	node.IsConst = isConst
+	for this.MatchSymbol(`*`) {
+		node = NewPointerNode(node)
+		node.IsConst = this.MatchIdentifier(`const`)
+	}
+	return node
+}
+
+// Evaluate computes the integer value of the expression, lookup returns the value of an identifier.
+// It fails for identifiers without a value, floating point values and invalid operations such as a
+// division by zero.
+func (this *Expression) Evaluate(lookup func(identifier string) (int64, bool)) (int64, bool) {
+	switch this.ExpressionType {
+	case kExpressionLiteral:
+		switch this.ConstType {
+		case kInt64, kChar:
+			return this.Int64Const, true
+		case kBoolean:
+			if this.BoolConst {
+				return 1, true
+			}
+			return 0, true
+		}
+	case kExpressionIdentifier:
+		if lookup != nil {
+			return lookup(this.Identifier)
+		}
+	case kExpressionUnary:
+		value, ok := this.Operand.Evaluate(lookup)
+		if !ok {
+			return 0, false
+		}
+		switch this.Operator {
+		case `-`:
+			return -value, true
+		case `+`:
+			return value, true
+		case `~`:
+			return ^value, true
+		case `!`:
+			return boolValue(value == 0), true
+		}
+	case kExpressionBinary:
+		left, ok := this.Left.Evaluate(lookup)
+		if !ok {
+			return 0, false
+		}
+		right, ok := this.Right.Evaluate(lookup)
+		if !ok {
+			return 0, false
+		}
+		return evaluateBinary(this.Operator, left, right)
+	case kExpressionConditional:
+		condition, ok := this.Condition.Evaluate(lookup)
+		if !ok {
+			return 0, false
+		}
+		if condition != 0 {
+			return this.Then.Evaluate(lookup)
+		}
+		return this.Else.Evaluate(lookup)
+	case kExpressionCast:
+		if this.Operand.ExpressionType == kExpressionLiteral && this.Operand.ConstType == kFloat64 {
+			return castValue(this.Type, int64(this.Operand.Float64Const))
+		}
+		value, ok := this.Operand.Evaluate(lookup)
+		if !ok {
+			return 0, false
+		}
+		return castValue(this.Type, value)
+	case kExpressionSizeof:
+		if this.Type == nil {
+			return 0, false
+		}
+		if this.Type.NodeType == kPointer {
+			return 8, true
+		}
+		if one, ok := lookupBuiltinType(this.Type.LiteralName); ok {
+			return one.size, true
+		}
+	}
+	return 0, false
+}
+
+// castValue converts the value to an integral type, a cast to any other named type such as an
+// enum keeps the value
+func castValue(node *TypeNode, value int64) (int64, bool) {
+	if node.NodeType != kLiteral {
+		return 0, false
+	}
+	one, ok := lookupBuiltinType(node.LiteralName)
+	if !ok {
+		if node.LiteralName == `void` {
+			return 0, false
+		}
+		return value, true
+	}
+	if !one.integral {
+		return 0, false
+	}
+	if node.LiteralName == `bool` {
+		return boolValue(value != 0), true
+	}
+	if one.size >= 8 {
+		return value, true
+	}
+	bits := uint(one.size * 8)
+	value &= 1<<bits - 1
+	if one.signed && value&(1<<(bits-1)) != 0 {
+		value -= 1 << bits
+	}
+	return value, true
+}
+
+func evaluateBinary(operator string, left int64, right int64) (int64, bool) {
+	switch operator {
+	case `*`:
+		return left * right, true
+	case `/`:
+		if right == 0 {
+			return 0, false
+		}
+		return left / right, true
+	case `%`:
+		if right == 0 {
+			return 0, false
+		}
+		return left % right, true
+	case `+`:
+		return left + right, true
+	case `-`:
+		return left - right, true
+	case `<<`:
+		if right < 0 || right >= 64 {
+			return 0, false
+		}
+		return left << uint(right), true
+	case `>>`:
+		if right < 0 || right >= 64 {
+			return 0, false
+		}
+		return left >> uint(right), true
+	case `<`:
+		return boolValue(left < right), true
+	case `<=`:
+		return boolValue(left <= right), true
+	case `>`:
+		return boolValue(left > right), true
+	case `>=`:
+		return boolValue(left >= right), true
+	case `==`:
+		return boolValue(left == right), true
+	case `!=`:
+		return boolValue(left != right), true
+	case `&`:
+		return left & right, true
+	case `^`:
+		return left ^ right, true
+	case `|`:
+		return left | right, true
+	case `&&`:
+		return boolValue(left != 0 && right != 0), true
+	case `||`:
+		return boolValue(left != 0 || right != 0), true
+	}
+	return 0, false
+}
+
+func boolValue(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,164 @@
+package ymdCppHeaderParser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseExpression(t *testing.T) {
+	for source, expected := range map[string]int64{
+		`1 + 2 * 3`:                      7,
+		`(1 + 2) * 3`:                    9,
+		`1 << 4 | 1`:                     17,
+		`0x10 >> 2`:                      4,
+		`10-1`:                           9,
+		`10 -1 * 2`:                      8,
+		`-(3) + ~0`:                      -4,
+		`!0 && 2 > 1`:                    1,
+		`7 % 4 == 3`:                     1,
+		`1 ^ 3 & 2`:                      3,
+		`A * 2 + ns::B`:                  22,
+		`true + false`:                   1,
+		`100 / 7 / 2`:                    7,
+		`1 || 0 && 0`:                    1,
+		`0x7fffffff + 1`:                 0x80000000,
+		`1 ? 2 : 3`:                      2,
+		`0 ? 2 : A > 5 ? 4 : 5`:          4,
+		`(unsigned char)300`:             44,
+		`(signed char)200`:               -56,
+		`static_cast<int>(2.9)`:          2,
+		`int(A) + long unsigned(1)`:      11,
+		`(int)-1`:                        -1,
+		`sizeof(unsigned long long)`:     8,
+		`sizeof(std::uint16_t)`:          2,
+		`sizeof(const char *)`:           8,
+		`sizeof(unsigned __int128)`:      16,
+		`static_cast<Color>(3)`:          3,
+		`(bool)5 + sizeof(short int)`:    3,
+		`(std::uint8_t)(A * 30) | 0x100`: 300 - 256 + 0x100,
+		`'a' + 1`:                        98,
+		`'\n' | '\0'`:                    10,
+		`L'\x41' - '\101'`:               0,
+		`'\''`:                           39,
+	} {
+		expression, err := ParseExpression(source)
+		assert(err == nil, source, err)
+		value, ok := expression.Evaluate(func(identifier string) (int64, bool) {
+			switch identifier {
+			case `A`:
+				return 10, true
+			case `ns::B`:
+				return 2, true
+			}
+			return 0, false
+		})
+		assert(ok && value == expected, source, value)
+	}
+
+	for _, source := range []string{`1 / 0`, `1 << 64`, `C + 1`, `1.5`, `"a"`, `f(1, 2)`, `(double)1`, `sizeof(A)`} {
+		expression, err := ParseExpression(source)
+		assert(err == nil, source, err)
+		_, ok := expression.Evaluate(nil)
+		assert(!ok, source)
+	}
+
+	for _, source := range []string{``, `1 +`, `(1`, `1 2`, `{}`, `1 ? 2`, `int`, `static_cast(1)`} {
+		_, err := ParseExpression(source)
+		assert(err != nil, source)
+	}
+}
+
+func TestParseExpressionTree(t *testing.T) {
+	expression, err := ParseExpression(`std::max(a, b ? 1 : 2)`)
+	assert(err == nil, err)
+	assert(expression.ExpressionType == kExpressionCall && expression.Identifier == `std::max`)
+	assert(len(expression.Arguments) == 2)
+	assert(expression.Arguments[1].ExpressionType == kExpressionConditional)
+	assert(expression.Arguments[1].Condition.Identifier == `b`)
+
+	expression, err = ParseExpression(`(const unsigned int *)p`)
+	assert(err == nil, err)
+	assert(expression.ExpressionType == kExpressionCast && expression.Type.NodeType == kPointer)
+	assert(expression.Type.PointerBase.LiteralName == `unsigned int` && expression.Type.PointerBase.IsConst)
+}
+
+func TestParser_Evaluate(t *testing.T) {
+	p := NewParser([]byte(`
+#define BASE 0x10
+#define SHIFT (BASE >> 2)
+#define LOOP LOOP + 1
+#define MAX(a, b) ((a) > (b) ? (a) : (b))
+#define MULTI 1 + \
+	2
+enum E { A = BASE | 1, B = 1 << SHIFT, C = MULTI, D = LOOP, F = MAX(1, 2) };
+#undef BASE
+void f(int a = SHIFT * 2, const char *s = "x", int c = foo());
+`))
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	enumerators := file.Enums[0].Enumerators
+	assert(enumerators[0].Value == 17 && enumerators[1].Value == 16 && enumerators[2].Value == 3)
+	assert(!enumerators[3].HasValue && !enumerators[4].HasValue)
+	assert(enumerators[4].ParsedExpression.ExpressionType == kExpressionCall)
+
+	args := file.Functions[0].Arguments
+	_, ok := p.Evaluate(args[0].ParsedDefaultValue)
+	assert(!ok, `BASE is undefined`)
+	assert(args[1].ParsedDefaultValue.StringConst == `x`)
+	assert(args[2].ParsedDefaultValue.ExpressionType == kExpressionCall)
+
+	value, ok := p.Evaluate(&Expression{ExpressionType: kExpressionIdentifier, Identifier: `E::B`})
+	assert(ok && value == 16)
+}
+
+func TestParser_EvaluateDefaultValues(t *testing.T) {
+	p := NewParser([]byte(`
+#define FLAG 4
+void f(int a = 1 << 3, int b = 1 + 2, unsigned c = 0u | FLAG, int d = 1 ? 2 : 3, double e = -1.5, int g = 7);
+`))
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	args := file.Functions[0].Arguments
+	assert(len(args) == 6, len(args))
+	assert(args[0].DefaultValue == `1 << 3` && args[2].DefaultValue == `0u | FLAG`, args[0].DefaultValue)
+	for i, want := range []int64{8, 3, 4, 2} {
+		value, ok := p.Evaluate(args[i].ParsedDefaultValue)
+		assert(ok && value == want, args[i].DefaultValue, value)
+	}
+	assert(args[4].DefaultValue == `-1.5` && args[4].ParsedDefaultValue != nil)
+	assert(args[5].Name == `g` && args[5].DefaultValue == `7`)
+}
+
+func TestParser_CharLiterals(t *testing.T) {
+	p := NewParserWithOptions([]byte(`
+enum E { A = ',', B, C = '\n', D = ')' };
+void f(char c = ')', char d = '\'', int e = 1);
+TPROPERTY(Separator = ';') char sep;
+`), ParserOptions{PropertyNameMacro: `TPROPERTY`})
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	enumerators := file.Enums[0].Enumerators
+	assert(len(enumerators) == 4, len(enumerators))
+	assert(enumerators[0].Expression == `','` && enumerators[0].Value == ',' && enumerators[1].Value == ','+1)
+	assert(enumerators[2].Value == '\n' && enumerators[3].Value == ')')
+
+	args := file.Functions[0].Arguments
+	assert(len(args) == 3 && args[0].DefaultValue == `')'` && args[1].DefaultValue == `'\''`, marshalJson(args))
+	assert(args[0].ParsedDefaultValue.ConstType == kChar)
+	assert(file.Fields[0].Meta.Entries[0].Value.ConstType == kChar)
+	upstream := MarshalUpstreamJson(file, ``)
+	assert(strings.Contains(upstream, `"defaultValue":")"`) && strings.Contains(upstream, `"meta":{"Separator":";"}`), upstream)
+}
+
+func TestParser_EvaluateMacroChain(t *testing.T) {
+	source := "#define M0 UNDEFINED\n"
+	for i := 1; i < 64; i++ {
+		source += fmt.Sprintf("#define M%v M%v + M%v\n", i, i-1, i-1)
+	}
+	source += "enum E { A = M63, B = (1 ? 2 : M63) };"
+	file, err := NewParser([]byte(source)).ParseAll()
+	assert(err == nil, err)
+	enumerators := file.Enums[0].Enumerators
+	assert(!enumerators[0].HasValue && enumerators[1].Value == 2)
+}
@@ -1,6 +1,9 @@
 package ymdCppHeaderParser
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/json"
+)
 
 func marshalJson(v interface{}) string {
 	if v == nil {
@@ -9,3 +12,55 @@ func marshalJson(v interface{}) string {
 	data, _ := json.Marshal(v)
 	return string(data)
 }
+
+// marshalJsonIndent marshals v without escaping html characters, an empty indent produces compact output
+func marshalJsonIndent(v interface{}, indent string) string {
+	data, _ := encodeJson(v)
+	if indent == `` {
+		return string(data)
+	}
+	buf := bytes.NewBuffer(nil)
+	_ = json.Indent(buf, data, ``, indent)
+	return buf.String()
+}
+
+func encodeJson(v interface{}) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// jsonObject is a json object which keeps its keys in insertion order
+type jsonObject []jsonField
+
+type jsonField struct {
+	key   string
+	value interface{}
+}
+
+func (this *jsonObject) set(key string, value interface{}) {
+	*this = append(*this, jsonField{key: key, value: value})
+}
+
+func (this jsonObject) MarshalJSON() ([]byte, error) {
+	ss := bytes.NewBufferString(`{`)
+	for idx, one := range this {
+		if idx > 0 {
+			ss.WriteString(`,`)
+		}
+		key, _ := encodeJson(one.key)
+		ss.Write(key)
+		ss.WriteString(`:`)
+		value, err := encodeJson(one.value)
+		if err != nil {
+			return nil, err
+		}
+		ss.Write(value)
+	}
+	ss.WriteString(`}`)
+	return ss.Bytes(), nil
+}
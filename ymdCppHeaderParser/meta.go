@@ -0,0 +1,62 @@
+package ymdCppHeaderParser
+
+type MetaType string
+
+const (
+	kMetaConst      MetaType = `kMetaConst`
+	kMetaIdentifier MetaType = `kMetaIdentifier`
+	kMetaObject     MetaType = `kMetaObject`
+	kMetaList       MetaType = `kMetaList`
+)
+
+// MetaValue is a value of the meta sequence of an annotation macro:
+// TPROPERTY(Range(Min=0, Max=10), Tooltip="x", Tags={A, B})
+type MetaValue struct {
+	MetaType MetaType `json:",omitempty"`
+	Line     int      `json:",omitempty"`
+	Column   int      `json:",omitempty"`
+
+	// kMetaConst, classified like the token it comes from
+	ConstType    ConstType `json:",omitempty"`
+	StringConst  string    `json:",omitempty"`
+	BoolConst    bool      `json:",omitempty"`
+	Int64Const   int64     `json:",omitempty"`
+	Float64Const float64   `json:",omitempty"`
+
+	// kMetaIdentifier, e.g. Category=Gameplay or Type=ns::Class
+	Identifier string `json:",omitempty"`
+
+	// kMetaObject, e.g. Range(Min=0, Max=10)
+	Entries []*MetaEntry `json:",omitempty"`
+
+	// kMetaList, e.g. Tags={A, B}
+	Items []*MetaValue `json:",omitempty"`
+}
+
+type MetaEntry struct {
+	Key    string     `json:",omitempty"`
+	Value  *MetaValue `json:",omitempty"` // nil for a key without value
+	Line   int        `json:",omitempty"`
+	Column int        `json:",omitempty"`
+}
+
+func NewMetaObject(line int, column int) *MetaValue {
+	return &MetaValue{
+		MetaType: kMetaObject,
+		Line:     line,
+		Column:   column,
+	}
+}
+
+// Lookup returns the value of the key of an object, ok is false if the key is not present
+func (this *MetaValue) Lookup(key string) (value *MetaValue, ok bool) {
+	if this == nil || this.MetaType != kMetaObject {
+		return nil, false
+	}
+	for _, one := range this.Entries {
+		if one.Key == key {
+			return one.Value, true
+		}
+	}
+	return nil, false
+}
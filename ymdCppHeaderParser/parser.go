@@ -1,7 +1,9 @@
 package ymdCppHeaderParser
 
 import (
+	"fmt"
 	"log"
+	"strings"
 )
 
 type ScopeType string
@@ -24,73 +26,357 @@ type Scope struct {
 	scopeType                ScopeType
 	name                     string
 	currentAccessControlType AccessControlType
+	members                  *Members
+	isInline                 bool   // inline namespace
+	class                    *Class // the class of a kClass scope
+}
+
+// ParserOptions configures the parser. The macros are the annotations recognised in front of declarations,
+// e.g. TCLASS(Serializable, Name="x") class Foo { ... };
+type ParserOptions struct {
+	ClassNameMacro    string `json:",omitempty"`
+	EnumNameMacro     string `json:",omitempty"`
+	FunctionNameMacro string `json:",omitempty"`
+	PropertyNameMacro string `json:",omitempty"`
+	// Only report the declarations preceded by one of the macros, like the C++ header-parser tool does.
+	// Members of a class that is not reported are not reported either.
+	AnnotatedOnly bool `json:",omitempty"`
+	// Skip the statements which can not be parsed and continue after them, ParseAll then returns
+	// the declarations of the whole file and all the diagnostics
+	Recover bool `json:",omitempty"`
+	// Export or visibility macros which may precede the class name, e.g. MYLIB_API of class MYLIB_API Foo.
+	// An unlisted macro is only recognised in front of the name of a class definition.
+	ExportMacros []string `json:",omitempty"`
 }
 
 type Parser struct {
 	Tokenizer
 	scopes      [64]Scope
 	topScopeIdx int
+	file        *File
+	options     ParserOptions
+
+	// Annotation waiting for the declaration that follows the macro
+	annotationMacro string
+	annotationPos   int // start of the pending annotation, the documentation comment is in front of it
+	annotationMeta  *MetaValue
+
+	// Template head waiting for the class or function that follows, see parseTemplate
+	pendingTemplate *Template
+
+	// Attributes waiting for the declaration that follows, see parseAttributes
+	pendingAttributes []*Attribute
+	attributesPos     int // end of the last attribute specifier taken into pendingAttributes
+
+	linkage string // Language of the enclosing linkage specification, e.g. C of extern "C" { ... }
+
+	nesting int // Depth of the recursive descent, see enter
+
+	concepts   map[string]bool   // The concepts defined so far by their plain and qualified names
+	types      map[string]bool   // The classes, enums and type aliases declared so far by their plain and qualified names
+	// Parameters of the template heads around the cursor, see isUnknownTypeName
+	templateParameters []*TemplateParameter
+	constants  map[string]int64  // Values of the enumerators by their plain and qualified names
+	defines    map[string]string // Source of the object-like macros
+	evaluating map[string]bool   // The macros being evaluated, a macro can not refer to itself
+	evaluated  map[string]*int64 // Values of the macros, nil if they have none. Reset by every change.
+	cycles     int               // Counts the macros found referring to themselves
+
+	diagnostics Diagnostics
 
 	debug bool
 }
 
+// maxNesting bounds the recursion of the parser so that no input can overflow the stack
+const maxNesting = 256
+
 func NewParser(input []byte) *Parser {
+	return NewParserWithOptions(input, ParserOptions{})
+}
+
+func NewParserWithOptions(input []byte, options ParserOptions) *Parser {
 	this := &Parser{}
+	this.options = options
 
 	// Pass the input to the tokenizer
 	this.Tokenizer = *NewTokenizer(input, 1)
+	this.file = &File{}
 	// Reset scope
-	topScope := this.scopes[this.topScopeIdx]
+	topScope := &this.scopes[this.topScopeIdx]
 	topScope.name = ``
 	topScope.scopeType = kGlobal
 	topScope.currentAccessControlType = kPublic
+	topScope.members = &this.file.Members
 	return this
 }
 
-func (this *Parser) ParseAll() {
+// ParseAll parses all statements in the file and returns the declarations found.
+// On error the declarations parsed so far are returned along with a *ParseError,
+// in recovery mode along with the Diagnostics of all the statements skipped.
+func (this *Parser) ParseAll() (file *File, err error) {
+	file = this.file
+	defer this.catchParseError(&err)
 	// Parse all statements in the file
-	for this.ParseStatement() {
+	for this.parseStatement() {
+	}
+	if len(this.diagnostics) != 0 {
+		return file, this.diagnostics
+	}
+	return file, nil
+}
+
+// Diagnostics returns the errors recovered from so far, see ParserOptions.Recover
+func (this *Parser) Diagnostics() Diagnostics {
+	return this.diagnostics
+}
+
+// File returns the declarations parsed so far
+func (this *Parser) File() *File {
+	return this.file
+}
+
+// ParseStatement parses the next statement, ok is false at the end of the input
+func (this *Parser) ParseStatement() (ok bool, err error) {
+	defer this.catchParseError(&err)
+	return this.parseStatement(), nil
+}
+
+func (this *Parser) ParseDeclaration(token *Token) (err error) {
+	defer this.catchParseError(&err)
+	this.parseDeclaration(token)
+	return nil
+}
+
+func (this *Parser) ParseDirective() (err error) {
+	defer this.catchParseError(&err)
+	this.parseDirective()
+	return nil
+}
+
+func (this *Parser) ParseNamespace() (err error) {
+	defer this.catchParseError(&err)
+	this.parseNamespace()
+	return nil
+}
+
+func (this *Parser) ParseClass() (err error) {
+	defer this.catchParseError(&err)
+	this.parseClass()
+	return nil
+}
+
+func (this *Parser) ParseEnum() (err error) {
+	defer this.catchParseError(&err)
+	this.parseEnum()
+	return nil
+}
+
+func (this *Parser) ParseFunction() (err error) {
+	defer this.catchParseError(&err)
+	this.parseFunction()
+	return nil
+}
+
+// ParseProperty parses an annotated property, token is the annotation macro
+func (this *Parser) ParseProperty(token *Token) (err error) {
+	defer this.catchParseError(&err)
+	this.parseProperty(token)
+	return nil
+}
+
+func (this *Parser) ParseTypeNode() (node *TypeNode, err error) {
+	defer this.catchParseError(&err)
+	return this.parseTypeNode(), nil
+}
+
+// ParseDeclarator parses a type with a declarator and returns the declared name, which is empty for an
+// abstract declarator such as the one of int (*)[4]
+func (this *Parser) ParseDeclarator() (node *TypeNode, name string, err error) {
+	defer this.catchParseError(&err)
+	node, nameToken := this.parseDeclarator(this.parseTypeNode(), false, true)
+	return node, nameToken.Mtoken, nil
+}
+
+// ParseMetaSequence parses the key value pairs of an annotation macro after the opening (
+func (this *Parser) ParseMetaSequence() (meta *MetaValue, err error) {
+	defer this.catchParseError(&err)
+	return this.parseMetaSequence(), nil
+}
+
+// enter guards the recursive descent against too deep nesting, defer the returned function
+func (this *Parser) enter(funcId string) func() {
+	if this.nesting >= maxNesting {
+		this.panicf(funcId, `Nesting too deep`)
+	}
+	this.nesting++
+	return func() {
+		this.nesting--
 	}
 }
 
-func (this *Parser) ParseStatement() bool {
+func (this *Parser) parseStatement() bool {
+	this.pendingAttributes = nil
+	if this.options.Recover {
+		return this.recoverStatement()
+	}
 	var token Token
 	if !this.GetToken(&token, false, false) {
 		return false
 	}
-	if !this.ParseDeclaration(&token) {
+	if !this.parseDeclaration(&token) {
 		return false
 	}
 
 	return true
 }
 
-func (this *Parser) ParseDeclaration(token *Token) bool {
+// recoverStatement parses the next statement, if it fails the error is recorded as a diagnostic
+// and the statement is skipped
+func (this *Parser) recoverStatement() (ok bool) {
+	var startToken Token
+	if !this.GetToken(&startToken, false, false) {
+		return false
+	}
+
+	topScopeIdx := this.topScopeIdx
+	nesting := this.nesting
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		parseError, isParseError := r.(*ParseError)
+		if !isParseError {
+			parseError = this.newParseError(`internal `, fmt.Sprint(r))
+		}
+		// Forget the state of the statement and continue after it
+		this.topScopeIdx = topScopeIdx
+		this.nesting = nesting
+		this.annotationMacro = ``
+		this.annotationMeta = nil
+		this.pendingTemplate = nil
+		this.pendingAttributes = nil
+		this.UngetToken(&startToken)
+		this.resynchronize()
+		if this.cursorPos <= startToken.MstartPos {
+			// Make progress at least by one token, e.g. an unexpected } in the global scope
+			var token Token
+			this.GetToken(&token, false, false)
+		}
+
+		this.diagnostics = append(this.diagnostics, &Diagnostic{
+			ParseError:         parseError,
+			SkippedStartLine:   startToken.MstartLine,
+			SkippedStartColumn: this.columnOf(startToken.MstartPos),
+			SkippedEndLine:     this.cursorLine,
+			SkippedEndColumn:   this.columnOf(this.cursorPos),
+		})
+		ok = true
+	}()
+
+	return this.parseDeclaration(&startToken)
+}
+
+// resynchronize skips to the end of the statement like skipDeclaration does,
+// but stops in front of a } which closes the enclosing scope
+func (this *Parser) resynchronize() {
+	var token Token
+	this.skipStatement(&token, true)
+}
+
+func (this *Parser) parseDeclaration(token *Token) bool {
 	const funcId = `8w3c6jsa `
 
 	this.debugPrintf(funcId, "token %v", marshalJson(token))
 
+	if this.isAttributeStart(token) {
+		this.UngetToken(token)
+		this.parseAttributes()
+		if !this.GetToken(token, false, false) {
+			this.panicf(funcId, `Unexpected end of file`)
+		}
+		return this.parseDeclaration(token)
+	}
+
+	if token.MtokenType == kIdentifier {
+		switch token.Mtoken {
+		case this.options.PropertyNameMacro:
+			return this.parseProperty(token)
+		case this.options.ClassNameMacro:
+			return this.parseMacroMeta(token) && this.parseClass()
+		case this.options.EnumNameMacro:
+			if !this.parseMacroMeta(token) {
+				return false
+			}
+			this.parseEnum()
+			return true
+		case this.options.FunctionNameMacro:
+			return this.parseMacroMeta(token) && this.parseFunction()
+		}
+	}
+
 	switch token.Mtoken {
 	case `#`:
 		this.UngetToken(token)
-		return this.ParseDirective()
+		return this.parseDirective()
 	case `namespace`:
 		this.UngetToken(token)
-		return this.ParseNamespace()
+		return this.parseNamespace()
+	case `extern`:
+		var next Token
+		if this.GetToken(&next, false, false) {
+			if next.MtokenType == kConst && next.MconstType == kString {
+				this.UngetToken(token)
+				return this.parseLinkageSpecification()
+			}
+			if next.Mtoken == `template` {
+				// Explicit instantiation declaration, e.g. extern template class Foo<int>;
+				return this.parseDeclaration(&next)
+			}
+			this.UngetToken(&next)
+		}
+	case `inline`:
+		if this.MatchIdentifier(`namespace`) {
+			this.UngetToken(token)
+			return this.parseNamespace()
+		}
 	case `;`:
 		return true
 	case `enum`:
 		this.UngetToken(token)
-		this.ParseEnum()
+		this.parseEnum()
 		return true
-	case `class`, `struct`:
+	case `class`, `struct`, `union`:
 		this.UngetToken(token)
-		return this.ParseClass()
+		if this.isClassHead(false) {
+			return this.parseClass()
+		}
+		// A declaration using an elaborated type, e.g. struct Foo *next;
+		if !this.GetToken(token, false, false) {
+			return false
+		}
 	case `template`:
-		return this.SkipDeclaration(token)
+		this.UngetToken(token)
+		return this.parseTemplate()
+	case `typedef`:
+		this.UngetToken(token)
+		return this.parseTypedef()
+	case `static_assert`, `_Static_assert`:
+		this.UngetToken(token)
+		return this.parseStaticAssert()
+	case `friend`:
+		if this.isFriendClass() {
+			return this.parseFriendClass(token)
+		}
+	case `concept`:
+		this.UngetToken(token)
+		return this.parseConcept()
+	case `using`:
+		this.UngetToken(token)
+		return this.parseUsing()
 	}
-	if this.ParseAccessControl(token, &this.scopes[this.topScopeIdx].currentAccessControlType) {
-		this.RequireSymbol(`:`)
+	if this.ParseAccessControl(token, &this.topScope().currentAccessControlType) {
+		this.requireSymbol(`:`)
 		this.debugPrintf(funcId, "is access control")
 		return true
 	}
@@ -103,56 +389,131 @@ func (this *Parser) ParseDeclaration(token *Token) bool {
 	isConstExpr := false // method
 	isStatic := false    // method & property
 
-	isMutable := false // property
+	isExplicit := false // method
+
+	isMutable := false   // property
+	isExtern := false    // property
+	isConstinit := false // property
 	for {
 		if !isVirtual && this.MatchIdentifier(`virtual`) {
 			isVirtual = true
 		} else if !isInline && this.MatchIdentifier(`inline`) {
 			isInline = true
-		} else if !isConstExpr && this.MatchIdentifier(`constexpr`) {
+		} else if !isConstExpr && (this.MatchIdentifier(`constexpr`) || this.MatchIdentifier(`consteval`)) {
 			isConstExpr = true
+		} else if !isConstinit && this.MatchIdentifier(`constinit`) {
+			isConstinit = true
 		} else if !isStatic && this.MatchIdentifier(`static`) {
 			isStatic = true
+		} else if !isExplicit && this.MatchIdentifier(`explicit`) {
+			isExplicit = true
+			// explicit(bool), see parseFunction
+			if this.MatchSymbol(`(`) {
+				this.scanParenthesized()
+			}
 		} else if !isMutable && this.MatchIdentifier(`mutable`) {
 			isMutable = true
+		} else if !isExtern && this.MatchIdentifier(`extern`) {
+			isExtern = true
+		} else if this.MatchIdentifier(`friend`) {
+			// friend function, see parseFunction
+		} else if this.parseAttributes() {
+			continue
 		} else {
 			break
 		}
 	}
 
+	// Constructors, destructors and conversion operators have no type
+	if this.isUntypedFunction() {
+		this.debugPrintf(funcId, "token is method without type")
+		this.UngetToken(token)
+		return this.parseFunction()
+	}
+	var next Token
+
 	// Parse the type
-	if !this.ParseType() {
+	typeNode := this.parseTypeNode()
+	if typeNode == nil {
 		return false
 	}
+	this.parseAttributes()
 
+	if this.MatchIdentifier(`operator`) { // is operator overload
+		this.UngetToken(token)
+		return this.parseFunction()
+	}
+
+	specifiers := Field{
+		IsStatic:    isStatic,
+		IsMutable:   isMutable,
+		IsExtern:    isExtern,
+		IsConstinit: isConstinit,
+	}
+	base := declaratorBase(typeNode)
+	// Nested declarator, e.g. void (*callback)(int); or int (*matrix)[4];
+	if isNested, isNamed := this.scanNestedDeclarator(); isNested && isNamed {
+		node, nameToken := this.parseDeclarator(typeNode, false, true)
+		if node.NodeType == kFunction { // a function returning a pointer to a function or an array
+			this.UngetToken(token)
+			return this.parseFunction()
+		}
+		return this.parseFieldDeclarators(token, base, node, nameToken.Mtoken, specifiers)
+	}
 	var nameToken Token
 	if !this.GetIdentifier(&nameToken) {
+		if this.GetToken(&next, false, false) {
+			this.UngetToken(&next)
+			if next.Mtoken == `:` { // unnamed bit-field, e.g. int : 0;
+				return this.parseFieldDeclarators(token, base, typeNode, ``, specifiers)
+			}
+		}
 		this.panicf(funcId, `Expected a property or method name`)
 	}
 	this.debugPrintf(funcId, "nameToken %v", marshalJson(nameToken))
+	this.parseAttributes()
 
-	var next Token
 	if !this.GetToken(&next, false, false) {
-		panic(`ekuqkxjtz9`)
+		this.panicf(funcId, `Unexpected end of file`)
 	}
 	switch next.Mtoken {
-	case `;`: // is property
+	case `;`, `[`, `:`, `=`, `{`, `,`: // is property
 		this.debugPrintf(funcId, "token is property")
-		return true
+		this.UngetToken(&next)
+		return this.parseFieldDeclarators(token, base, typeNode, nameToken.Mtoken, specifiers)
 	case `(`: // is method
 		this.debugPrintf(funcId, "token is method")
 		this.UngetToken(token)
-		return this.ParseFunction()
+		return this.parseFunction()
+	case `::`, `<`, `<>`:
+		this.UngetToken(&nameToken)
+		if this.isQualifiedFunction() { // a member defined outside of its class, e.g. void A<T>::f(U u) {}
+			this.UngetToken(token)
+			return this.parseFunction()
+		}
+		if next.Mtoken != `::` && this.pendingTemplate != nil && this.isFunctionSpecialization() { // is specialization of a function template
+			this.UngetToken(token)
+			return this.parseFunction()
+		}
+		if next.Mtoken == `::` || this.pendingTemplate != nil {
+			// The definition of a member declared in its class, e.g. int A::count = 0;
+			// or of a specialization of a variable template, e.g. template <> int size<char> = 1;
+			this.debugPrintf(funcId, "skip definition of a declared variable")
+			return this.skipDeclaration(token)
+		}
+		this.GetIdentifier(&nameToken)
+		this.GetToken(&next, false, false)
 	}
-	this.debugPrintf(funcId, "skip unknown token")
-	return this.SkipDeclaration(token);
+	this.UngetToken(&next)
+	this.panicf(funcId, `Unexpected %v behind %v`, next.Mtoken, nameToken.Mtoken)
+	return false
 }
 
-func (this *Parser) ParseDirective() bool {
+func (this *Parser) parseDirective() bool {
 	const funcId = `f4haccj6 `
 	var token Token
 
-	this.RequireSymbol(`#`)
+	this.requireSymbol(`#`)
 
 	// Check the compiler directive
 	if !this.GetIdentifier(&token) {
@@ -161,20 +522,38 @@ func (this *Parser) ParseDirective() bool {
 	this.debugPrintf(funcId, "token %v", marshalJson(token))
 
 	multiLineEnabled := false
+	var defineToken Token
+	defineStart := -1
 	switch token.Mtoken {
 	case `define`:
 		multiLineEnabled = true
+		// Only object-like macros are constants, the ( of a function-like macro follows the name directly
+		if this.GetIdentifier(&defineToken) && this.peek() != '(' {
+			defineStart = this.cursorPos
+		}
+	case `undef`:
+		var undefToken Token
+		if this.GetIdentifier(&undefToken) {
+			delete(this.defines, undefToken.Mtoken)
+		}
 	case `include`:
 		var includeToken Token
-		this.GetToken(&includeToken, true, false)
+		if this.GetToken(&includeToken, true, false) && includeToken.MtokenType == kConst {
+			this.file.Includes = append(this.file.Includes, &Include{
+				Path:     includeToken.MstringConst,
+				IsSystem: this.input[includeToken.MstartPos] == '<',
+				Line:     includeToken.MstartLine,
+				startPos: token.MstartPos,
+			})
+		}
 		this.debugPrintf(funcId, "includeToken %v", marshalJson(includeToken))
 	}
 
 	// Skip past the end of the token
-	var lastChar byte = '\n'
 	for {
 		// Skip to the end of the line
 		var c byte
+		var lastChar byte = '\n'
 		for {
 			if this.is_eof() {
 				break
@@ -183,20 +562,40 @@ func (this *Parser) ParseDirective() bool {
 			if c == '\n' {
 				break
 			}
-			lastChar = c
+			if c != '\r' {
+				lastChar = c
+			}
 		}
 
-		if multiLineEnabled && lastChar == '\\' {
+		if multiLineEnabled && lastChar == '\\' && !this.is_eof() {
 			continue
 		} else {
 			break
 		}
 	}
 
+	if defineStart >= 0 || token.Mtoken == `undef` {
+		this.evaluated = nil
+		this.evaluating = nil
+	}
+	if defineStart >= 0 {
+		if this.defines == nil {
+			this.defines = map[string]string{}
+		}
+		value := strings.NewReplacer("\\\r\n", " ", "\\\n", " ").Replace(string(this.input[defineStart:this.cursorPos]))
+		this.defines[defineToken.Mtoken] = strings.TrimSpace(value)
+	}
+	return true
+}
+
+func (this *Parser) skipDeclaration(token *Token) bool {
+	this.skipStatement(token, false)
 	return true
 }
 
-func (this *Parser) SkipDeclaration(token *Token) bool {
+// skipStatement skips the tokens up to the ; or the } which ends the statement, a block is skipped as a whole.
+// With stopAtScopeEnd it stops in front of a } which closes the enclosing scope.
+func (this *Parser) skipStatement(token *Token, stopAtScopeEnd bool) {
 	scopeDepth := 0
 	for this.GetToken(token, false, false) {
 		if token.Mtoken == `;` && scopeDepth == 0 {
@@ -208,84 +607,262 @@ func (this *Parser) SkipDeclaration(token *Token) bool {
 		}
 
 		if token.Mtoken == `}` {
+			if stopAtScopeEnd && scopeDepth == 0 {
+				this.UngetToken(token)
+				break
+			}
 			scopeDepth--
 			if scopeDepth == 0 {
 				break
 			}
 		}
 	}
-
-	return true
 }
 
-func (this *Parser) ParseEnum() {
+func (this *Parser) parseEnum() {
 	const funcId = `d3gpz066 `
-	if !this.MatchIdentifier(`enum`) {
+	var startToken Token
+	if !this.GetIdentifier(&startToken) || startToken.Mtoken != `enum` {
 		this.panicf(funcId, `require "enum" identifier`)
 	}
 	// C++1x enum class type?
 	isEnumClass := this.MatchIdentifier(`class`)
+	this.parseAttributes()
 
 	this.debugPrintf(funcId, "isEnumClass %v", isEnumClass)
 
-	// Parse enum name
+	// Parse enum name, an anonymous enum only declares its enumerators, e.g. enum { A, B };
 	var enumToken Token
 	if !this.GetIdentifier(&enumToken) {
-		this.panicf(funcId, "Missing enum name")
+		if isEnumClass || (!this.MatchSymbol(`{`) && !this.MatchSymbol(`:`)) {
+			this.panicf(funcId, "Missing enum name")
+		}
+		this.UngetToken(&enumToken)
+		enumToken = Token{MstartPos: enumToken.MstartPos, MstartLine: enumToken.MstartLine}
 	}
 
 	this.debugPrintf(funcId, "enum %v", marshalJson(enumToken))
 
-	// Parse C++1x enum base
-	if isEnumClass && this.MatchSymbol(`:`) {
-		var baseToken Token
-		if !this.GetIdentifier(&baseToken) {
+	enum := &Enum{
+		Declaration: this.newDeclaration(enumToken.Mtoken, &startToken),
+		IsClass:     isEnumClass,
+	}
+	// Parse C++1x enum base, e.g. std::uint8_t or unsigned long
+	if this.MatchSymbol(`:`) {
+		for {
+			var token Token
+			if !this.GetIdentifier(&token) {
+				break
+			}
+			this.UngetToken(&token)
+			if enum.BaseType != `` {
+				enum.BaseType += ` `
+			}
+			enum.BaseType += this.parseTypeNodeDeclarator()
+		}
+		if enum.BaseType == `` {
 			this.panicf(funcId, "Missing enum type specifier")
 		}
-		// Validate base token
+	}
+
+	if this.MatchSymbol(`;`) { // opaque declaration
+		this.debugPrintf(funcId, `forward declaration.`)
+		return
 	}
 
 	// Require opening brace
-	this.RequireSymbol(`{`)
+	this.requireSymbol(`{`)
 
 	// Parse all the values
 	var token Token
 	for this.GetIdentifier(&token) {
 		this.debugPrintf(funcId, "enum object %v", marshalJson(token))
+		enumerator := &Enumerator{
+			Name:    token.Mtoken,
+			Line:    token.MstartLine,
+			Comment: this.LeadingComment(token.MstartPos),
+		}
+		enum.Enumerators = append(enum.Enumerators, enumerator)
+		// A [[deprecated]]
+		this.parseAttributes()
+		enumerator.Attributes = this.takeAttributes()
+		endPos := this.cursorPos
 		// Parse constant
 		if this.MatchSymbol(`=`) {
-			// Just parse the value, not doing anything with it atm
-			value := ``
-			for this.GetToken(&token, false, false) &&
-				(token.MtokenType != kSymbol || (token.Mtoken != `,` && token.Mtoken != `}`)) {
-				value += token.Mtoken
+			// Take the source up to the next , or } outside of parentheses
+			startPos := this.cursorPos
+			endPos = startPos
+			depth := 0
+			for this.GetToken(&token, false, false) {
+				if token.MtokenType == kSymbol {
+					if depth == 0 && (token.Mtoken == `,` || token.Mtoken == `}`) {
+						break
+					}
+					switch token.Mtoken {
+					case `(`, `[`:
+						depth++
+					case `)`, `]`:
+						depth--
+					}
+				}
+				endPos = this.cursorPos
 			}
-			this.debugPrintf(funcId, "value %v", marshalJson(value))
+			enumerator.Expression = strings.TrimSpace(string(this.input[startPos:endPos]))
+			this.debugPrintf(funcId, "value %v", marshalJson(enumerator.Expression))
 			this.UngetToken(&token)
 		}
+		this.evaluateEnumerator(enum, enumerator)
 		// Next value?
-		if !this.MatchSymbol(`,`) {
+		next := this.MatchSymbol(`,`)
+		enumerator.TrailingComment = this.TrailingComment(endPos)
+		if !next {
 			break
 		}
 	}
 
-	this.RequireSymbol(`}`)
-	this.RequireSymbol(`;`)
+	this.requireSymbol(`}`)
+	this.requireSymbol(`;`)
+	this.endDeclaration(&enum.Declaration)
+	this.addEnumConstants(enum)
+	this.declareType(enum.Name)
+
+	if this.isReported(&enum.Declaration) {
+		members := this.topScope().members
+		members.Enums = append(members.Enums, enum)
+	}
+}
+
+// evaluateEnumerator computes the value of the enumerator, without an expression it is the value of
+// the previous enumerator plus one
+func (this *Parser) evaluateEnumerator(enum *Enum, enumerator *Enumerator) {
+	if enumerator.Expression == `` {
+		if len(enum.Enumerators) == 1 {
+			enumerator.HasValue = true
+			return
+		}
+		previous := enum.Enumerators[len(enum.Enumerators)-2]
+		enumerator.Value = previous.Value + 1
+		enumerator.HasValue = previous.HasValue
+		return
+	}
+	enumerator.ParsedExpression, _ = ParseExpression(enumerator.Expression)
+	if enumerator.ParsedExpression == nil {
+		return
+	}
+	enumerator.Value, enumerator.HasValue = enumerator.ParsedExpression.Evaluate(func(identifier string) (int64, bool) {
+		// The enumerators declared before this one
+		for _, one := range enum.Enumerators {
+			if one.HasValue && (one.Name == identifier || enum.Name+`::`+one.Name == identifier) {
+				return one.Value, true
+			}
+		}
+		return this.lookupConstant(identifier)
+	})
+}
+
+// Evaluate computes the value of an expression of the file, it knows the values of the enumerators
+// and the #define constants parsed so far. A nil expression, e.g. one ParseExpression failed on, has no value.
+func (this *Parser) Evaluate(expression *Expression) (int64, bool) {
+	if expression == nil {
+		return 0, false
+	}
+	return expression.Evaluate(this.lookupConstant)
+}
+
+// lookupConstant returns the value of an enumerator or an object-like macro
+func (this *Parser) lookupConstant(identifier string) (int64, bool) {
+	if value, ok := this.constants[identifier]; ok {
+		return value, true
+	}
+	if value, ok := this.evaluated[identifier]; ok {
+		if value == nil {
+			return 0, false
+		}
+		return *value, true
+	}
+	source, ok := this.defines[identifier]
+	if !ok {
+		return 0, false
+	}
+	if this.evaluating[identifier] {
+		this.cycles++
+		return 0, false
+	}
+	expression, err := ParseExpression(source)
+	if err != nil {
+		return 0, false
+	}
+	if this.evaluating == nil {
+		this.evaluating = map[string]bool{}
+		this.evaluated = map[string]*int64{}
+	}
+	this.evaluating[identifier] = true
+	cycles := this.cycles
+	value, ok := expression.Evaluate(this.lookupConstant)
+	delete(this.evaluating, identifier)
+	if ok {
+		this.evaluated[identifier] = &value
+	} else if cycles == this.cycles {
+		// A failure caused by a macro referring to itself depends on where the evaluation started
+		this.evaluated[identifier] = nil
+	}
+	return value, ok
+}
+
+// addEnumConstants makes the enumerators available to the expressions that follow.
+// Unscoped enumerators are found by their name as well.
+func (this *Parser) addEnumConstants(enum *Enum) {
+	if this.constants == nil {
+		this.constants = map[string]int64{}
+	}
+	this.evaluated = nil
+	this.evaluating = nil
+	scopes := []string{this.scopeName()}
+	if this.isInlineScoped() {
+		scopes = append(scopes, this.visibleScopeName())
+	}
+	for _, one := range enum.Enumerators {
+		if !one.HasValue {
+			continue
+		}
+		names := []string{}
+		if !enum.IsClass {
+			names = append(names, one.Name)
+		}
+		if enum.Name != `` {
+			names = append(names, enum.Name+`::`+one.Name)
+		}
+		for _, name := range names {
+			this.constants[name] = one.Value
+			for _, scope := range scopes {
+				this.constants[joinQualifiedName(scope, name)] = one.Value
+			}
+		}
+	}
 }
 
-func (this *Parser) ParseMacroMeta() bool {
-	this.RequireSymbol(`(`)
-	if !this.ParseMetaSequence() {
+// parseMacroMeta parses the meta sequence following an annotation macro and attaches it
+// to the next declaration
+func (this *Parser) parseMacroMeta(macroToken *Token) bool {
+	this.requireSymbol(`(`)
+	meta := this.parseMetaSequence()
+	if meta == nil {
 		return false
 	}
+	this.annotationMacro = macroToken.Mtoken
+	this.annotationPos = macroToken.MstartPos
+	this.annotationMeta = meta
 	// Possible ;
 	this.MatchSymbol(`;`)
 
 	return true
 }
 
-func (this *Parser) ParseMetaSequence() bool {
+// parseMetaSequence parses the key value pairs after an opening (, including the closing )
+func (this *Parser) parseMetaSequence() *MetaValue {
 	const funcId = `hky9quq0 `
+	defer this.enter(funcId)()
+	object := NewMetaObject(this.cursorLine, this.columnOf(this.cursorPos))
 	if !this.MatchSymbol(`)`) {
 		for {
 			// Parse key value
@@ -293,16 +870,20 @@ func (this *Parser) ParseMetaSequence() bool {
 			if !this.GetIdentifier(&keyToken) {
 				this.panicf(funcId, "Expected identifier in meta sequence")
 			}
+			entry := &MetaEntry{
+				Key:    keyToken.Mtoken,
+				Line:   keyToken.MstartLine,
+				Column: this.columnOf(keyToken.MstartPos),
+			}
+			object.Entries = append(object.Entries, entry)
 
 			// Simple value?
 			if this.MatchSymbol(`=`) {
-				var token Token
-				if !this.GetToken(&token, false, false) {
-					this.panicf(funcId, `Expected token`)
-				}
-			} else if (this.MatchSymbol(`(`)) { // Compound value
-				if !this.ParseMetaSequence() {
-					return false
+				entry.Value = this.parseMetaValue()
+			} else if this.MatchSymbol(`(`) { // Compound value
+				entry.Value = this.parseMetaSequence()
+				if entry.Value == nil {
+					return nil
 				}
 				// No value
 			} else {
@@ -317,23 +898,70 @@ func (this *Parser) ParseMetaSequence() bool {
 		this.MatchSymbol(`)`)
 	}
 
-	return true
+	return object
+}
+
+// parseMetaValue parses the value after key= in a meta sequence
+func (this *Parser) parseMetaValue() *MetaValue {
+	const funcId = `r5w2mhx8 `
+	defer this.enter(funcId)()
+	var token Token
+	if !this.GetToken(&token, false, false) {
+		this.panicf(funcId, `Expected token`)
+	}
+	value := &MetaValue{
+		Line:   token.MstartLine,
+		Column: this.columnOf(token.MstartPos),
+	}
+
+	switch {
+	case token.MtokenType == kConst:
+		value.MetaType = kMetaConst
+		value.ConstType = token.MconstType
+		value.StringConst = token.MstringConst
+		value.BoolConst = token.MboolConst
+		value.Int64Const = token.Mint64Const
+		value.Float64Const = token.Mfloat64Const
+	case token.MtokenType == kIdentifier || token.Mtoken == `::`:
+		this.UngetToken(&token)
+		value.MetaType = kMetaIdentifier
+		value.Identifier = this.parseTypeNodeDeclarator()
+	case token.Mtoken == `{`:
+		value.MetaType = kMetaList
+		if !this.MatchSymbol(`}`) {
+			for {
+				value.Items = append(value.Items, this.parseMetaValue())
+				if !this.MatchSymbol(`,`) {
+					break
+				}
+			}
+			this.requireSymbol(`}`)
+		}
+	case token.Mtoken == `(`:
+		return this.parseMetaSequence()
+	default:
+		this.panicf(funcId, `Unexpected meta value %v`, token.Mtoken)
+	}
+	return value
 }
 
-func (this *Parser) PushScope(name string, scopeType ScopeType, accessControlType AccessControlType) {
+func (this *Parser) pushScope(name string, scopeType ScopeType, accessControlType AccessControlType, members *Members) {
 	const funcId = `njxt77ngz9 `
 	if this.topScopeIdx >= len(this.scopes)-1 {
 		this.panicf(funcId, `Max scope depth`)
 	}
 
 	this.topScopeIdx++
-	topScope := this.scopes[this.topScopeIdx]
+	topScope := &this.scopes[this.topScopeIdx]
 	topScope.scopeType = scopeType
 	topScope.name = name
 	topScope.currentAccessControlType = accessControlType
+	topScope.members = members
+	topScope.isInline = false
+	topScope.class = nil
 }
 
-func (this *Parser) PopScope() {
+func (this *Parser) popScope() {
 	const funcId = `v83qqwx728 `
 	if this.topScopeIdx == 0 {
 		this.panicf(funcId, `Scope error`)
@@ -342,29 +970,209 @@ func (this *Parser) PopScope() {
 	this.topScopeIdx--
 }
 
-func (this *Parser) ParseNamespace() bool {
-	const funcId = `l4u2kamr `
+func (this *Parser) topScope() *Scope {
+	return &this.scopes[this.topScopeIdx]
+}
+
+// scopeName returns the fully-qualified name of the current scope, e.g. ns1::ns2::ClassType
+func (this *Parser) scopeName() string {
+	return this.joinScopeNames(true)
+}
+
+// visibleScopeName is the scope name without inline namespaces
+func (this *Parser) visibleScopeName() string {
+	return this.joinScopeNames(false)
+}
+
+func (this *Parser) joinScopeNames(withInline bool) string {
+	name := ``
+	for i := 1; i <= this.topScopeIdx; i++ {
+		if this.scopes[i].name == `` || (!withInline && this.scopes[i].isInline) {
+			// Anonymous classes and namespaces add no name
+			continue
+		}
+		if name != `` {
+			name += `::`
+		}
+		name += this.scopes[i].name
+	}
+	return name
+}
+
+// isInlineScoped tells whether an inline namespace encloses the current scope
+func (this *Parser) isInlineScoped() bool {
+	for i := 1; i <= this.topScopeIdx; i++ {
+		if this.scopes[i].isInline {
+			return true
+		}
+	}
+	return false
+}
+
+// newDeclaration describes an entity of the current scope whose declaration begins at startToken,
+// it takes over the pending annotation
+func (this *Parser) newDeclaration(name string, startToken *Token) Declaration {
+	commentPos := startToken.MstartPos
+	if this.annotationMacro != `` {
+		commentPos = this.annotationPos
+	}
+	comment := this.LeadingComment(commentPos)
+	declaration := Declaration{
+		Name:       name,
+		Scope:      this.scopeName(),
+		Access:     this.topScope().currentAccessControlType,
+		Line:       startToken.MstartLine,
+		Macro:      this.annotationMacro,
+		Meta:       this.annotationMeta,
+		Comment:    comment,
+		Doc:        ParseDocComment(comment),
+		Attributes: this.takeAttributes(),
+		startPos:   startToken.MstartPos,
+	}
+	if this.isInlineScoped() {
+		declaration.VisibleScope = this.visibleScopeName()
+		declaration.isInlineScoped = true
+	}
+	this.annotationMacro = ``
+	this.annotationMeta = nil
+	return declaration
+}
+
+// endDeclaration picks up the comment behind the declaration, the cursor is at its end
+func (this *Parser) endDeclaration(declaration *Declaration) {
+	declaration.TrailingComment = this.TrailingComment(this.cursorPos)
+}
+
+// isReported tells whether the declaration belongs in the model, see ParserOptions.AnnotatedOnly
+func (this *Parser) isReported(declaration *Declaration) bool {
+	return !this.options.AnnotatedOnly || declaration.Macro != ``
+}
+
+func (this *Parser) addField(field *Field) {
+	if this.isReported(&field.Declaration) {
+		field.Linkage = this.currentLinkage()
+		members := this.topScope().members
+		members.Fields = append(members.Fields, field)
+	}
+}
+
+// parseLinkageSpecification parses extern "C" { ... } or extern "C" in front of a single declaration
+func (this *Parser) parseLinkageSpecification() bool {
+	const funcId = `v2k8rw6j `
+	var startToken Token
+	if !this.GetIdentifier(&startToken) || startToken.Mtoken != `extern` {
+		this.panicf(funcId, `Missing "extern" identifier`)
+	}
+	var linkageToken Token
+	if !this.GetToken(&linkageToken, false, false) || linkageToken.MtokenType != kConst || linkageToken.MconstType != kString {
+		this.panicf(funcId, `Expected linkage, e.g. "C"`)
+	}
+	outerLinkage := this.linkage
+	this.linkage = linkageToken.Mtoken
+	defer func() {
+		this.linkage = outerLinkage
+	}()
+
+	if this.MatchSymbol(`{`) {
+		// The declarations of the block belong to the enclosing scope
+		for !this.MatchSymbol(`}`) {
+			if !this.parseStatement() {
+				this.panicf(funcId, `Missing "}" at the end of extern "%v"`, linkageToken.Mtoken)
+			}
+		}
+		return true
+	}
 	var token Token
+	if !this.GetToken(&token, false, false) {
+		this.panicf(funcId, `Unexpected end of file`)
+	}
+	return this.parseDeclaration(&token)
+}
+
+// currentLinkage is the language linkage of the functions and variables declared at the cursor,
+// empty for C++, including an explicit extern "C++". Class members always have C++ linkage.
+func (this *Parser) currentLinkage() string {
+	if this.topScope().scopeType == kClass || this.linkage == `C++` {
+		return ``
+	}
+	return this.linkage
+}
 
-	if !this.GetIdentifier(&token) || token.Mtoken != `namespace` {
+// parseNamespace parses a namespace definition, including nested (a::b), inline and anonymous
+// namespaces, or a namespace alias
+func (this *Parser) parseNamespace() bool {
+	const funcId = `l4u2kamr `
+	var startToken Token
+	if !this.GetIdentifier(&startToken) {
+		this.panicf(funcId, `Missing "namespace" identifier`)
+	}
+	isInline := startToken.Mtoken == `inline`
+	if isInline && !this.MatchIdentifier(`namespace`) || !isInline && startToken.Mtoken != `namespace` {
 		this.panicf(funcId, `Missing "namespace" identifier`)
 	}
 
-	if !this.GetIdentifier(&token) {
-		this.panicf(funcId, "Missing namespace name")
+	type namespaceName struct {
+		name     string
+		isInline bool
 	}
+	this.parseAttributes()
+	var names []namespaceName
+	if this.MatchSymbol(`{`) {
+		// Anonymous namespace
+		names = append(names, namespaceName{isInline: isInline})
+	} else {
+		for {
+			// namespace a::inline b { ... }
+			isNestedInline := len(names) != 0 && this.MatchIdentifier(`inline`)
+			var token Token
+			if !this.GetIdentifier(&token) {
+				this.panicf(funcId, "Missing namespace name")
+			}
+			names = append(names, namespaceName{name: token.Mtoken, isInline: isInline || isNestedInline})
+			isInline = false
+			if !this.MatchSymbol(`::`) {
+				break
+			}
+		}
 
-	this.RequireSymbol(`{`)
+		if len(names) == 1 && !names[0].isInline && this.MatchSymbol(`=`) {
+			// namespace fs = std::filesystem;
+			alias := &NamespaceAlias{
+				Declaration: this.newDeclaration(names[0].name, &startToken),
+				Target:      this.parseTypeNodeDeclarator(),
+			}
+			this.requireSymbol(`;`)
+			this.endDeclaration(&alias.Declaration)
+			members := this.topScope().members
+			members.NamespaceAliases = append(members.NamespaceAliases, alias)
+			return true
+		}
+		this.requireSymbol(`{`)
+	}
 
-	this.PushScope(token.Mtoken, kNamespace, kPublic)
+	var namespaces []*Namespace
+	for _, one := range names {
+		namespace := &Namespace{
+			Declaration: this.newDeclaration(one.name, &startToken),
+			IsInline:    one.isInline,
+		}
+		members := this.topScope().members
+		members.Namespaces = append(members.Namespaces, namespace)
+		this.pushScope(one.name, kNamespace, kPublic, &namespace.Members)
+		this.topScope().isInline = one.isInline
+		namespaces = append(namespaces, namespace)
+	}
 
 	for !this.MatchSymbol(`}`) {
-		if !this.ParseStatement() {
-			return false
+		if !this.parseStatement() {
+			this.panicf(funcId, `Missing "}" at the end of namespace %v`, this.scopeName())
 		}
 	}
 
-	this.PopScope()
+	for i := len(namespaces) - 1; i >= 0; i-- {
+		this.popScope()
+		this.endDeclaration(&namespaces[i].Declaration)
+	}
 	return true
 }
 
@@ -382,49 +1190,127 @@ func (this *Parser) ParseAccessControl(token *Token, accessControlType *AccessCo
 	return true
 }
 
-func (this *Parser) ParseClass() bool {
+// parseClass parses a class, struct or union definition or forward declaration. The definition may be
+// preceded by typedef and followed by declarators, e.g. typedef struct { ... } Point, *PPoint;
+func (this *Parser) parseClass() bool {
 	const funcId = `z0dnwwg6 `
 
 	var startAccessControlType = kPrivate
 
-	if this.MatchIdentifier(`class`) {
+	var startToken Token
+	if !this.GetIdentifier(&startToken) {
+		this.panicf(funcId, `Missing "class", "struct" or "union"`)
+	}
+	isTypedef := startToken.Mtoken == `typedef`
+	keywordToken := startToken
+	if isTypedef && !this.GetIdentifier(&keywordToken) {
+		this.panicf(funcId, `Missing "class", "struct" or "union"`)
+	}
+	switch keywordToken.Mtoken {
+	case `class`:
 		startAccessControlType = kPrivate
-	} else if this.MatchIdentifier(`struct`) {
+	case `struct`, `union`:
 		startAccessControlType = kPublic
-	} else {
-		this.panicf(funcId, `Missing "class" or "struct"`)
-	}
-	// Get the class name
-	var classNameToken Token
-	if !this.GetIdentifier(&classNameToken) {
-		this.panicf(funcId, `Missing class name `)
+	default:
+		this.panicf(funcId, `Missing "class", "struct" or "union"`)
 	}
-	this.debugPrintf(funcId, "class begin %v", marshalJson(classNameToken))
-
-	if this.MatchSymbol(`;`) { // forward declaration
-		this.debugPrintf(funcId, `forward declaration.`)
-		return true
+	templateHead := this.takeTemplate()
+	this.parseAttributes()
+	exportMacro := ``
+	for {
+		isUnknownExportMacro := this.isUnknownExportMacro(isTypedef)
+		var token Token
+		if this.GetIdentifier(&token) {
+			if isUnknownExportMacro || this.isExportMacro(token.Mtoken) {
+				exportMacro = token.Mtoken
+				this.parseAttributes()
+				continue
+			}
+			this.UngetToken(&token)
+		}
+		break
+	}
+	// Get the class name, an anonymous class has none
+	var classNameToken Token
+	if !this.GetIdentifier(&classNameToken) {
+		if !this.MatchSymbol(`{`) {
+			this.panicf(funcId, `Missing class name`)
+		}
+		this.UngetToken(&classNameToken)
+		classNameToken = Token{}
+	}
+	// A nested class defined outside of the enclosing one, e.g. class Outer::Inner { ... }; or struct A<T>::B { ... };
+	qualifier := ``
+	for {
+		mark := Token{MstartPos: this.cursorPos, MstartLine: this.cursorLine}
+		this.parseTemplateArgumentList()
+		if !this.MatchSymbol(`::`) {
+			// The template arguments of a specialization are parsed below
+			this.UngetToken(&mark)
+			break
+		}
+		qualifier = joinQualifiedName(qualifier, classNameToken.Mtoken)
+		if !this.GetIdentifier(&classNameToken) {
+			this.panicf(funcId, `Missing class name`)
+		}
+	}
+	this.debugPrintf(funcId, "class begin %v", marshalJson(classNameToken))
+
+	isFinal := false
+	if classNameToken.Mtoken != `` {
+		// Explicit or partial specialization
+		templateHead.SpecializationArguments = this.parseTemplateArgumentList()
+		isFinal = this.MatchIdentifier(`final`)
+
+		if !isTypedef && this.MatchSymbol(`;`) { // forward declaration
+			this.debugPrintf(funcId, `forward declaration.`)
+			this.declareType(joinQualifiedName(qualifier, classNameToken.Mtoken))
+			// Forward declarations are not part of the model, drop their annotation
+			this.annotationMacro = ``
+			this.annotationMeta = nil
+			this.pendingAttributes = nil
+			return true
+		}
+	}
+
+	class := &Class{
+		Declaration: this.newDeclaration(classNameToken.Mtoken, &startToken),
+		Template:    templateHead,
+		IsStruct:    keywordToken.Mtoken == `struct`,
+		IsUnion:     keywordToken.Mtoken == `union`,
+		IsFinal:     isFinal,
+		ExportMacro: exportMacro,
+	}
+	if qualifier != `` {
+		class.Scope = joinQualifiedName(class.Scope, qualifier)
+		if class.isInlineScoped {
+			class.VisibleScope = joinQualifiedName(class.VisibleScope, qualifier)
+		}
 	}
 
 	// Match base types
 	if this.MatchSymbol(`:`) {
 		for {
-			var accessOrName Token
-			if !this.GetIdentifier(&accessOrName) {
-				this.panicf(funcId, `Missing class or access control specifier`)
-			}
-
-			// Parse the access control specifier
-			accessControlType := startAccessControlType
-			if !this.ParseAccessControl(&accessOrName, &accessControlType) {
-				this.UngetToken(&accessOrName)
+			base := &BaseClass{
+				Access: startAccessControlType,
 			}
-			var baseClassName Token
-			if !this.GetIdentifier(&baseClassName) {
-				this.panicf(funcId, `Missing base class name`)
+			// The access control and virtual specifiers in any order
+			var token Token
+			for this.GetIdentifier(&token) {
+				if token.Mtoken == `virtual` {
+					base.IsVirtual = true
+				} else if !this.ParseAccessControl(&token, &base.Access) {
+					this.UngetToken(&token)
+					break
+				}
 			}
+			startPos := this.cursorPos
+			base.Type = this.parseType(false)
+			base.Name = strings.TrimSpace(string(this.input[startPos:this.cursorPos]))
+			base.IsPack = this.MatchSymbol(`...`)
 
-			this.debugPrintf(funcId, "base class %v", marshalJson(baseClassName))
+			this.debugPrintf(funcId, "base class %v", marshalJson(base))
+			class.Bases = append(class.Bases, base)
 
 			if !this.MatchSymbol(`,`) {
 				break
@@ -432,26 +1318,295 @@ func (this *Parser) ParseClass() bool {
 		}
 	}
 
-	this.RequireSymbol(`{`)
+	this.requireSymbol(`{`)
 
-	this.PushScope(classNameToken.Mtoken, kClass, startAccessControlType)
+	this.declareType(joinQualifiedName(qualifier, classNameToken.Mtoken))
+	if this.isReported(&class.Declaration) {
+		members := this.topScope().members
+		members.Classes = append(members.Classes, class)
+	}
+	this.pushScope(joinQualifiedName(qualifier, classNameToken.Mtoken), kClass, startAccessControlType, &class.Members)
+	this.topScope().class = class
 
 	for !this.MatchSymbol(`}`) {
-		if !this.ParseStatement() {
+		if !this.parseStatement() {
+			this.panicf(funcId, `Missing "}" at the end of class %v`, classNameToken.Mtoken)
+		}
+	}
+	this.popScope()
+
+	if this.MatchSymbol(`;`) {
+		if class.Name == `` && !isTypedef {
+			// The members of an anonymous struct or union are accessed as members of the enclosing scope
+			class.IsAnonymous = true
+			members := this.topScope().members
+			members.Fields = append(members.Fields, class.Fields...)
+		}
+	} else {
+		this.parseClassDeclarators(class, isTypedef)
+	}
+	this.endDeclaration(&class.Declaration)
+	this.debugPrintf(funcId, "class end %v", marshalJson(classNameToken))
+
+	return true
+}
+
+// isClassHead tells whether the class key at the cursor begins a class definition or a forward
+// declaration rather than a declaration using an elaborated type, e.g. struct Foo *next;
+// isTypedef tells that the class key follows a typedef, see isUnknownExportMacro.
+func (this *Parser) isClassHead(isTypedef bool) bool {
+	var startToken Token
+	if !this.GetToken(&startToken, false, false) {
+		return false
+	}
+	defer this.UngetToken(&startToken)
+
+	this.parseAttributeSpecifiers()
+	var token Token
+	if !this.GetToken(&token, false, false) {
+		return false
+	}
+	for token.MtokenType == kIdentifier && this.isExportMacro(token.Mtoken) {
+		this.parseAttributeSpecifiers()
+		if !this.GetToken(&token, false, false) {
 			return false
 		}
 	}
-	this.PopScope()
+	if token.MtokenType == kIdentifier {
+		this.UngetToken(&token)
+		if this.isUnknownExportMacro(isTypedef) {
+			return true
+		}
+		this.GetToken(&token, false, false)
+		if !this.skipClassName() || !this.GetToken(&token, false, false) {
+			return false
+		}
+	}
+	return token.Mtoken == `{` || token.Mtoken == `:` || token.Mtoken == `;`
+}
 
-	this.RequireSymbol(`;`)
-	this.debugPrintf(funcId, "class end %v", marshalJson(classNameToken))
+// skipClassName skips the rest of the class name behind its first identifier including the template arguments
+// of its qualifier and of a specialization and a final, e.g. the <T>::Inner<int> final of Outer<T>::Inner<int> final
+func (this *Parser) skipClassName() bool {
+	for {
+		if !this.skipTemplateArgumentList() {
+			return false
+		}
+		if !this.MatchSymbol(`::`) {
+			break
+		}
+		var token Token
+		if !this.GetIdentifier(&token) {
+			return false
+		}
+	}
+	this.MatchIdentifier(`final`)
+	return true
+}
 
+// isUnknownExportMacro tells whether the identifier behind the cursor is an export macro which is missing in
+// ParserOptions.ExportMacros, e.g. the MYLIB_API of class MYLIB_API Foo { ... }; or of class MYLIB_API Foo;
+// A declaration of a variable with an elaborated type, e.g. struct stat info; is read as a forward declaration
+// the same way, the variable must be declared without the class key, e.g. stat info;
+// Behind a typedef only a definition is recognised, typedef struct Foo Foo; names the type Foo.
+func (this *Parser) isUnknownExportMacro(isTypedef bool) bool {
+	mark := Token{MstartPos: this.cursorPos, MstartLine: this.cursorLine}
+	defer this.UngetToken(&mark)
+	var token Token
+	if !this.GetIdentifier(&token) || this.isExportMacro(token.Mtoken) || token.Mtoken == `final` {
+		return false
+	}
+	if !this.GetIdentifier(&token) || token.Mtoken == `final` {
+		return false
+	}
+	if !this.skipClassName() || !this.GetToken(&token, false, false) {
+		return false
+	}
+	return token.Mtoken == `{` || token.Mtoken == `:` || (!isTypedef && token.Mtoken == `;`)
+}
+
+// skipTemplateArgumentList skips the template arguments behind the cursor if there are any,
+// it fails if they are not closed before the end of the statement
+func (this *Parser) skipTemplateArgumentList() bool {
+	if !this.MatchSymbol(`<`) {
+		this.MatchSymbol(`<>`)
+		return true
+	}
+	depth := 0      // parentheses
+	angleDepth := 1 // template argument lists
+	for angleDepth > 0 {
+		var token Token
+		if !this.GetToken(&token, false, false) {
+			return false
+		}
+		switch token.Mtoken {
+		case `(`:
+			depth++
+		case `)`:
+			depth--
+		case `<`:
+			if depth == 0 {
+				angleDepth++
+			}
+		case `>`:
+			if depth == 0 {
+				angleDepth--
+			}
+		case `;`, `{`, `}`:
+			return false
+		}
+	}
+	return true
+}
+
+// isExportMacro tells whether the identifier is one of the export macros of the options
+func (this *Parser) isExportMacro(identifier string) bool {
+	for _, one := range this.options.ExportMacros {
+		if one == identifier {
+			return true
+		}
+	}
+	return false
+}
+
+// isFriendClass tells whether the friend declaration behind the cursor befriends a class rather than a
+// function, e.g. friend class Helper; or friend Helper;
+func (this *Parser) isFriendClass() bool {
+	mark := Token{MstartPos: this.cursorPos, MstartLine: this.cursorLine}
+	defer this.UngetToken(&mark)
+	var token Token
+	for this.GetToken(&token, false, false) {
+		if token.MtokenType == kSymbol {
+			switch token.Mtoken {
+			case `;`:
+				return true
+			case `(`, `{`, `}`:
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// parseFriendClass parses the friend declaration of a class behind the friend keyword
+func (this *Parser) parseFriendClass(startToken *Token) bool {
+	templateHead := this.takeTemplate()
+	this.parseAttributes()
+	this.pendingAttributes = nil
+	startPos := this.cursorPos
+	typeNode := this.parseType(false)
+	name := strings.TrimSpace(string(this.input[startPos:this.cursorPos]))
+	for _, keyword := range []string{`class `, `struct `, `union `} {
+		name = strings.TrimSpace(strings.TrimPrefix(name, keyword))
+	}
+	this.requireSymbol(`;`)
+	if class := this.topScope().class; class != nil && this.isReported(&class.Declaration) {
+		class.Friends = append(class.Friends, &Friend{
+			Template: templateHead,
+			Name:     name,
+			Type:     typeNode,
+			Line:     startToken.MstartLine,
+		})
+	}
+	// Like forward declarations friend declarations take no annotation
+	this.annotationMacro = ``
+	this.annotationMeta = nil
 	return true
 }
 
-func (this *Parser) ParseProperty(token *Token) bool {
+// parseStaticAssert parses static_assert(condition, "message"); the message is optional
+func (this *Parser) parseStaticAssert() bool {
+	const funcId = `b9v4ks2m `
+	var startToken Token
+	if !this.GetIdentifier(&startToken) {
+		this.panicf(funcId, `Missing "static_assert" identifier`)
+	}
+	this.requireSymbol(`(`)
+	// The message is the string literal behind the last comma outside of parentheses, a comma may
+	// also separate template arguments, e.g. static_assert(std::is_same<A, B>::value, "A is B");
+	startPos := this.cursorPos
+	endPos := -1
+	var message []string
+	depth := 0
+	var token Token
+	for {
+		if !this.GetToken(&token, false, false) {
+			this.panicf(funcId, `Missing ")"`)
+		}
+		if token.MtokenType == kSymbol {
+			if token.Mtoken == `,` && depth == 0 {
+				endPos = token.MstartPos
+				message = []string{}
+				continue
+			}
+			if token.Mtoken == `)` && depth == 0 {
+				break
+			}
+			switch token.Mtoken {
+			case `(`, `[`, `{`:
+				depth++
+			case `)`, `]`, `}`:
+				depth--
+			}
+		}
+		if message != nil && token.MtokenType == kConst && token.MconstType == kString {
+			message = append(message, token.MstringConst)
+		} else {
+			message = nil
+			endPos = -1
+		}
+	}
+	if endPos < 0 {
+		endPos = token.MstartPos
+	}
+	this.requireSymbol(`;`)
+
+	staticAssert := &StaticAssert{
+		Expression: strings.TrimSpace(string(this.input[startPos:endPos])),
+		Message:    strings.Join(message, ``),
+		Scope:      this.scopeName(),
+		Line:       startToken.MstartLine,
+	}
+	staticAssert.ParsedExpression, _ = ParseExpression(staticAssert.Expression)
+	members := this.topScope().members
+	members.StaticAsserts = append(members.StaticAsserts, staticAssert)
+	return true
+}
+
+// parseClassDeclarators parses the declarators behind the closing brace of a class up to the ;
+// as fields, or as type aliases of a typedef
+func (this *Parser) parseClassDeclarators(class *Class, isTypedef bool) {
+	const funcId = `c6y1ta9k `
+	for {
+		base := NewLiteralNode(class.Name)
+		typeNode, nameToken := this.parseDeclarator(base, false, isTypedef)
+		if nameToken.Mtoken == `` {
+			this.panicf(funcId, `Expected declarator name`)
+		}
+		if class.Name == `` && isTypedef && typeNode == base {
+			// typedef struct { ... } Point; names the class
+			class.Name = nameToken.Mtoken
+			base.LiteralName = class.Name
+		}
+
+		declaration := this.newDeclaration(nameToken.Mtoken, &nameToken)
+		if isTypedef {
+			alias := &TypeAlias{Declaration: declaration, Type: typeNode}
+			this.addTypeAlias(alias)
+		} else {
+			field := &Field{Declaration: declaration, Type: typeNode}
+			this.addField(field)
+		}
+		if !this.MatchSymbol(`,`) {
+			break
+		}
+	}
+	this.requireSymbol(`;`)
+}
+
+func (this *Parser) parseProperty(token *Token) bool {
 	const funcId = `ajqd8r4p4b `
-	if !this.ParseMacroMeta() {
+	if !this.parseMacroMeta(token) {
 		return false
 	}
 	// Process method specifiers in any particular order
@@ -467,7 +1622,8 @@ func (this *Parser) ParseProperty(token *Token) bool {
 		}
 	}
 	// Parse the type
-	if !this.ParseType() {
+	typeNode := this.parseTypeNode()
+	if typeNode == nil {
 		return false
 	}
 
@@ -484,17 +1640,152 @@ func (this *Parser) ParseProperty(token *Token) bool {
 		}
 	}
 
+	field := &Field{
+		Declaration: this.newDeclaration(nameToken.Mtoken, token),
+		Type:        typeNode,
+		IsStatic:    isStatic,
+		IsMutable:   isMutable,
+	}
+	this.endDeclaration(&field.Declaration)
+	this.addField(field)
 	return true
 }
 
-func (this *Parser) ParseFunction() bool {
+// parseFieldDeclarators parses the declarators of a variable declaration from behind the first name up
+// to the ;, e.g. the [16] of int data[16]; or the = 0, *z of int x = 0, *z; Each declarator is a field,
+// base is the type the declarators apply to.
+func (this *Parser) parseFieldDeclarators(startToken *Token, base *TypeNode, typeNode *TypeNode, name string, specifiers Field) bool {
+	const funcId = `r3n8ya5w `
+	var fields []*Field
+	for {
+		field := specifiers
+		field.Type = this.parseArrayExtents(typeNode)
+		this.parseAttributes()
+		if this.MatchSymbol(`:`) {
+			field.BitWidth = this.scanExpressionUntil(`,`, `;`, `=`, `{`)
+			field.ParsedBitWidth, _ = ParseExpression(field.BitWidth)
+		}
+		if this.MatchSymbol(`=`) {
+			field.DefaultValue = this.scanExpressionUntil(`,`, `;`)
+		} else if this.MatchSymbol(`{`) {
+			// Keep the braces of std::string name{"x"};
+			startPos := this.cursorPos - 1
+			this.scanExpressionUntil(`}`)
+			this.requireSymbol(`}`)
+			field.DefaultValue = string(this.input[startPos:this.cursorPos])
+		}
+		if field.DefaultValue != `` {
+			field.ParsedDefaultValue, _ = ParseExpression(field.DefaultValue)
+		}
+		field.Declaration = this.newDeclaration(name, startToken)
+		fields = append(fields, &field)
+		this.addField(&field)
+
+		if !this.MatchSymbol(`,`) {
+			break
+		}
+		// The pointer and reference modifiers belong to each declarator: int x, *y;
+		var nameToken Token
+		typeNode, nameToken = this.parseDeclarator(base, false, false)
+		if nameToken.Mtoken == `` {
+			this.panicf(funcId, `Expected a property name`)
+		}
+		name = nameToken.Mtoken
+	}
+	this.requireSymbol(`;`)
+	for _, one := range fields {
+		this.endDeclaration(&one.Declaration)
+	}
+	return true
+}
+
+// parseArrayExtents parses the [N] behind a declarator name, int m[2][3] is an array of 2 arrays of 3 ints
+func (this *Parser) parseArrayExtents(node *TypeNode) *TypeNode {
+	var sizes []string
+	for {
+		var token Token
+		if !this.GetToken(&token, false, false) {
+			break
+		}
+		if token.Mtoken != `[` || token.MtokenType != kSymbol || this.isAttributeStart(&token) {
+			this.UngetToken(&token)
+			break
+		}
+		sizes = append(sizes, this.scanExpressionUntil(`]`))
+		this.requireSymbol(`]`)
+	}
+	for i := len(sizes) - 1; i >= 0; i-- {
+		node = NewArrayNode(node, sizes[i])
+	}
+	return node
+}
+
+// scanExpressionUntil returns the source up to the first of the given symbols outside of parentheses,
+// brackets and braces and stops in front of it
+func (this *Parser) scanExpressionUntil(symbols ...string) string {
+	const funcId = `h2f5jm8c `
+	startPos := this.cursorPos
+	depth := 0
+	var token Token
+	for this.GetToken(&token, false, false) {
+		if token.MtokenType != kSymbol {
+			continue
+		}
+		if depth == 0 {
+			for _, one := range symbols {
+				if token.Mtoken == one {
+					this.UngetToken(&token)
+					return strings.TrimSpace(string(this.input[startPos:token.MstartPos]))
+				}
+			}
+		}
+		switch token.Mtoken {
+		case `(`, `[`, `{`:
+			depth++
+		case `)`, `]`, `}`:
+			depth--
+		}
+	}
+	this.panicf(funcId, `Unexpected end of file`)
+	return ``
+}
+
+// declaratorBase strips the pointer and reference modifiers of the first declarator of a declaration
+func declaratorBase(node *TypeNode) *TypeNode {
+	for {
+		switch node.NodeType {
+		case kPointer:
+			node = node.PointerBase
+		case kMemberPointer:
+			node = node.MemberPointerBase
+		case kReference:
+			node = node.ReferenceBase
+		case kLReference:
+			node = node.LReferenceBase
+		default:
+			return node
+		}
+	}
+}
+
+func (this *Parser) parseFunction() bool {
 	const funcId = `tom77xqc `
-	funcNode := NewFunctionNode()
+	var startToken Token
+	if !this.GetToken(&startToken, false, false) {
+		return false
+	}
+	this.UngetToken(&startToken)
+	templateHead := this.takeTemplate()
+
 	// Process method specifiers in any particular order
 	isVirtual := false
 	isInline := false
 	isConstExpr := false
 	isStatic := false
+	isExplicit := false
+	explicitExpression := ``
+	isFriend := false
+	isConsteval := false
 	for {
 		if !isVirtual && this.MatchIdentifier(`virtual`) {
 			isVirtual = true
@@ -502,70 +1793,147 @@ func (this *Parser) ParseFunction() bool {
 			isInline = true
 		} else if !isConstExpr && this.MatchIdentifier(`constexpr`) {
 			isConstExpr = true
+		} else if !isConsteval && this.MatchIdentifier(`consteval`) {
+			isConsteval = true
 		} else if !isStatic && this.MatchIdentifier(`static`) {
 			isStatic = true
+		} else if !isExplicit && this.MatchIdentifier(`explicit`) {
+			isExplicit = true
+			if this.MatchSymbol(`(`) {
+				explicitExpression = this.scanParenthesized()
+				expression, _ := ParseExpression(explicitExpression)
+				if value, ok := this.Evaluate(expression); ok && value == 0 {
+					// explicit(false)
+					isExplicit = false
+				}
+			}
+		} else if !isFriend && this.MatchIdentifier(`friend`) {
+			isFriend = true
+		} else if this.MatchIdentifier(`extern`) {
+			// Functions are extern anyway
+		} else if this.parseAttributes() {
+			continue
 		} else {
 			break
 		}
 	}
 
-	this.debugPrintf(funcId, "front property isVirtual=[%v] isInline=[%v] isConstExpr=[%v] isStatic=[%v]",
-		isVirtual, isInline, isConstExpr, isStatic)
+	this.debugPrintf(funcId, "front property isVirtual=[%v] isInline=[%v] isConstExpr=[%v] isStatic=[%v] isExplicit=[%v] isFriend=[%v]",
+		isVirtual, isInline, isConstExpr, isStatic, isExplicit, isFriend)
 
-	// Parse the return type
-	funcNode.FunctionReturns = this.ParseTypeNode()
-	this.debugPrintf(funcId, "retType %v", marshalJson(funcNode.FunctionReturns))
-	if funcNode.FunctionReturns == nil {
-		return false
-	}
-	// Parse the name of the method
+	// Parse the return type and the name of the method
+	functionKind := kOrdinaryFunction
+	var returnType *TypeNode
+	var operator string
+	var name string
 	var nameToken Token
-	if !this.GetIdentifier(&nameToken) {
-		this.panicf(funcId, `Expected method name`)
+	var declarator *TypeNode // The function type of a declarator which includes the parameter list
+	// The class of a member defined outside of it, e.g. the A of void A<T>::f() or of A<T>::~A()
+	mark := Token{MstartPos: this.cursorPos, MstartLine: this.cursorLine}
+	qualifier := this.parseNestedNameSpecifier()
+	switch {
+	case this.MatchSymbol(`~`):
+		functionKind = kDestructor
+		if !this.GetIdentifier(&nameToken) {
+			this.panicf(funcId, `Expected destructor name`)
+		}
+		name = `~` + nameToken.Mtoken
+	case this.MatchIdentifier(`operator`):
+		// operator const char *()
+		functionKind = kConversionOperator
+		startPos := this.cursorPos
+		returnType = this.parseType(false)
+		name = `operator ` + strings.Join(strings.Fields(string(this.input[startPos:this.cursorPos])), ` `)
+	case this.isConstructor(qualifier):
+		functionKind = kConstructor
+		this.GetIdentifier(&nameToken)
+		name = nameToken.Mtoken
+	default:
+		// The qualifier belongs to the return type
+		this.UngetToken(&mark)
+		returnType = this.parseTypeNode()
+		this.debugPrintf(funcId, "retType %v", marshalJson(returnType))
+		if returnType == nil {
+			return false
+		}
+		this.parseAttributes()
+		if isNested, isNamed := this.scanNestedDeclarator(); isNested && isNamed {
+			// A function returning a pointer to a function, e.g. void (*handler(int))(double)
+			declarator, nameToken = this.parseDeclarator(returnType, false, true)
+			if declarator.NodeType != kFunction || nameToken.Mtoken == `` {
+				this.panicf(funcId, `Expected method name`)
+			}
+			returnType = declarator.FunctionReturns
+			name = nameToken.Mtoken
+		} else if qualifier = this.parseNestedNameSpecifier(); this.MatchIdentifier(`operator`) {
+			functionKind = kOperator
+			operator = this.parseOperatorSymbol()
+			name = operatorName(operator)
+		} else if this.GetIdentifier(&nameToken) {
+			name = nameToken.Mtoken
+		} else {
+			this.panicf(funcId, `Expected method name`)
+		}
 	}
-	this.debugPrintf(funcId, "function name %v", marshalJson(nameToken))
-	this.MatchSymbol("(")
-	// Is there an argument list in the first place or is it closed right away?
-	if !this.MatchSymbol(`)`) {
+	this.debugPrintf(funcId, "function name %v", name)
+	this.parseAttributes()
+	// Explicit specialization of a function template
+	templateHead.SpecializationArguments = this.parseTemplateArgumentList()
+	function := &Function{
+		Declaration:  this.newDeclaration(name, &startToken),
+		Template:     templateHead,
+		FunctionKind: functionKind,
+		Operator:     operator,
+		ReturnType:   returnType,
+		IsVirtual:    isVirtual,
+		IsInline:     isInline,
+		IsConstExpr:  isConstExpr,
+		IsStatic:     isStatic,
+		IsExplicit:   isExplicit,
+		IsFriend:     isFriend,
+		IsConsteval:  isConsteval,
+
+		ExplicitExpression: explicitExpression,
+	}
+	if qualifier != `` {
+		function.Scope = joinQualifiedName(function.Scope, qualifier)
+		if function.isInlineScoped {
+			function.VisibleScope = joinQualifiedName(function.VisibleScope, qualifier)
+		}
+	}
+	if declarator != nil {
+		function.Arguments = declarator.FunctionArguments
+	} else if this.MatchSymbol("("); !this.MatchSymbol(`)`) {
+		// Is there an argument list in the first place or is it closed right away?
 		// Walk over all arguments
 		for i := 0; ; i++ {
 			// Get the type of the argument
-			var argTypeNode = this.ParseTypeNode()
+			this.parseAttributes()
+			var argTypeNode = this.parseTypeNode()
 			this.debugPrintf(funcId, "argTypeNode %v", marshalJson(argTypeNode))
 			if argTypeNode == nil {
 				return false
 			}
+			argument := &Argument{
+				Type:       argTypeNode,
+				Attributes: this.takeAttributes(),
+			}
+			function.Arguments = append(function.Arguments, argument)
 			// Optional argument name
-			ok := this.GetIdentifier(&nameToken)
-			if ok {
-				this.debugPrintf(funcId, "argument name %v", marshalJson(nameToken))
+			var argNameToken Token
+			argument.Type, argNameToken = this.parseDeclarator(argTypeNode, false, true)
+			if argNameToken.Mtoken != `` {
+				argument.Name = argNameToken.Mtoken
+				this.debugPrintf(funcId, "argument name %v", marshalJson(argNameToken))
 			} else {
 				this.debugPrintf(funcId, "argument no name")
 			}
-			funcNode.FunctionArguments = append(funcNode.FunctionArguments, &Argument{
-				Name: nameToken.Mtoken,
-				Type: argTypeNode,
-			})
 			// Parse default value
 			if this.MatchSymbol(`=`) {
-				defaultValue := ``
-				var token Token
-				this.GetToken(&token, false, false)
-				if token.MtokenType == kConst {
-					this.debugPrintf(funcId, "argument default value const %v", marshalJson(token))
-				} else {
-					for {
-						if token.Mtoken == `,` || token.Mtoken == `)` {
-							this.UngetToken(&token)
-							break
-						}
-						defaultValue += token.Mtoken
-						if !this.GetToken(&token, false, false) {
-							break
-						}
-					}
-					this.debugPrintf(funcId, "argument default value express %v", defaultValue)
-				}
+				// Take the source up to the next , or ) outside of brackets, e.g. 1 << 3 or std::string("x")
+				argument.DefaultValue = this.scanExpressionUntil(`,`, `)`)
+				this.debugPrintf(funcId, "argument default value %v", argument.DefaultValue)
+				argument.ParsedDefaultValue, _ = ParseExpression(argument.DefaultValue)
 			} else {
 				this.debugPrintf(funcId, "argument have not default value")
 			}
@@ -573,54 +1941,97 @@ func (this *Parser) ParseFunction() bool {
 				break
 			}
 		}
-		this.RequireSymbol(`)`)
+		this.requireSymbol(`)`)
 	}
 
-	// Optionally parse constness
-	ok := this.MatchIdentifier(`const`)
-	this.debugPrintf(funcId, "function is const %v", ok)
-	// Pure?
+	if function.Doc != nil {
+		function.Doc.matchArguments(function.Arguments)
+	}
+
+	this.parseFunctionQualifiers(function)
+	constrainUsedTemplateParameters(&function.Template, function.ReturnType, function.Arguments)
+	// Pure, defaulted or deleted?
 	if this.MatchSymbol(`=`) {
 		var token Token
-		if !this.GetToken(&token, false, false) || token.Mtoken != `0` {
-			this.panicf(funcId, `Expected nothing else than null`) //
+		if !this.GetToken(&token, false, false) {
+			this.panicf(funcId, `Expected 0, default or delete`)
+		}
+		switch token.Mtoken {
+		case `0`:
+			function.IsPure = true
+		case `default`:
+			function.IsDefault = true
+		case `delete`:
+			function.IsDeleted = true
+		default:
+			this.UngetToken(&token)
+			this.panicf(funcId, `Expected 0, default or delete`)
 		}
 		this.debugPrintf(funcId, `pure func `, marshalJson(token))
 	}
+	// Member initializer list of a constructor
+	if this.MatchSymbol(`:`) {
+		this.skipInitializerList()
+	}
 	// Skip either the ; or the body of the function
 	var skipToken Token
-	if !this.SkipDeclaration(&skipToken) {
+	if !this.skipDeclaration(&skipToken) {
 		return false
 	}
-
+	this.endDeclaration(&function.Declaration)
+
+	if this.isReported(&function.Declaration) {
+		function.Linkage = this.currentLinkage()
+		if class := this.topScope().class; isFriend && class != nil {
+			// A friend is no member of the class, it belongs to the scope enclosing the class
+			function.Scope = class.Scope
+			function.VisibleScope = class.VisibleScope
+			function.isInlineScoped = class.isInlineScoped
+			class.Friends = append(class.Friends, &Friend{
+				Name:     function.Name,
+				Function: function,
+				Line:     function.Line,
+			})
+			return true
+		}
+		members := this.topScope().members
+		members.Functions = append(members.Functions, function)
+	}
 	return true
 }
 
-func (this *Parser) ParseType() bool {
-	node := this.ParseTypeNode()
-	if node == nil {
-		return false
-	}
-	return true
+func (this *Parser) parseTypeNode() *TypeNode {
+	return this.parseType(true)
 }
 
-func (this *Parser) ParseTypeNode() *TypeNode {
+// parseType parses a type, a function type only if allowFunction is set. The target type of a
+// conversion operator is directly followed by the parameter list.
+func (this *Parser) parseType(allowFunction bool) *TypeNode {
 	const funcId = `f98vawvz `
+	defer this.enter(funcId)()
 	var node *TypeNode
-	var token Token
 
 	var (
 		isConst    = false
 		isVolatile = false
 		isMutable  = false
+		words      []string
 	)
+	// The keywords of a fundamental type may be mixed with the cv-qualifiers, e.g. unsigned const long
 	for {
+		var token Token
 		if !isConst && this.MatchIdentifier(`const`) {
 			isConst = true
 		} else if !isVolatile && this.MatchIdentifier(`volatile`) {
 			isVolatile = true
 		} else if !isMutable && this.MatchIdentifier(`mutable`) {
 			isMutable = true
+		} else if this.GetIdentifier(&token) {
+			if !builtinTypeWords[token.Mtoken] {
+				this.UngetToken(&token)
+				break
+			}
+			words = append(words, token.Mtoken)
 		} else {
 			break
 		}
@@ -628,8 +2039,22 @@ func (this *Parser) ParseTypeNode() *TypeNode {
 
 	// Parse a literal value
 	declarator := ``
+	isDecltype := false
 
-	declarator = this.ParseTypeNodeDeclarator()
+	if words != nil {
+		normalized, ok := normalizeBuiltinType(words)
+		if !ok {
+			this.panicf(funcId, `Invalid type "%v"`, strings.Join(words, ` `))
+		}
+		declarator = normalized
+	} else if this.MatchIdentifier(`decltype`) {
+		// The type of an expression is kept as its source, e.g. decltype(a * b) or decltype(auto)
+		isDecltype = true
+		this.requireSymbol(`(`)
+		declarator = `decltype(` + strings.Join(strings.Fields(this.scanParenthesized()), ` `) + `)`
+	} else {
+		declarator = this.parseTypeNodeDeclarator()
+	}
 
 	// Postfix const specifier
 	if this.MatchIdentifier(`const`) {
@@ -637,34 +2062,43 @@ func (this *Parser) ParseTypeNode() *TypeNode {
 	}
 
 	// Template?
-	if this.MatchSymbol(`<`) {
+	if words != nil || isDecltype {
+		node = NewLiteralNode(declarator)
+	} else if arguments := this.parseTemplateArgumentList(); arguments != nil {
 		templateNode := NewTemplateNode(declarator)
-		for {
-			node := this.ParseTypeNode()
-			if node == nil {
-				return nil
-			}
-			templateNode.TemplateArguments = append(templateNode.TemplateArguments, node)
-
-			if this.MatchSymbol(`,`) {
-				continue
-			} else {
-				break
-			}
-		}
-
-		if !this.MatchSymbol(`>`) {
-			this.panicf(funcId, `Expected closing > `)
-		}
-		node = templateNode 
+		templateNode.TemplateArguments = arguments
+		node = templateNode
 	} else {
 		node = NewLiteralNode(declarator)
 	}
+	// Constrained placeholder, e.g. std::integral auto
+	if words == nil && this.MatchIdentifier(`auto`) {
+		constraint := node
+		node = NewLiteralNode(`auto`)
+		node.Constraint = constraint
+		if this.MatchIdentifier(`const`) {
+			isConst = true
+		}
+	}
 
 	// Store gathered stuff
 	node.IsConst = isConst
 
-	// Check reference or pointer types
+	node, _ = this.parseDeclarator(node, true, allowFunction)
+
+	// This stuff refers to the top node
+	if isVolatile {
+		node.IsVolatile = true
+	}
+	node.IsMutable = isMutable
+
+	return node
+}
+
+// parsePointerOperators parses the reference, pointer and pointer to member modifiers of a type, e.g.
+// the * const * of char * const *
+func (this *Parser) parsePointerOperators(node *TypeNode) *TypeNode {
+	var token Token
 	for this.GetToken(&token, false, false) {
 		if token.Mtoken == `&` {
 			node = NewReferenceNode(node)
@@ -672,78 +2106,482 @@ func (this *Parser) ParseTypeNode() *TypeNode {
 			node = NewLReferenceNode(node)
 		} else if token.Mtoken == `*` {
 			node = NewPointerNode(node)
+		} else if class, ok := this.matchMemberPointer(&token); ok {
+			node = NewMemberPointerNode(node, class)
 		} else {
-			this.UngetToken(&token)
 			break
 		}
 
-		if this.MatchIdentifier(`const`) {
-			node.IsConst = true
+		for {
+			if this.MatchIdentifier(`const`) {
+				node.IsConst = true
+			} else if this.MatchIdentifier(`volatile`) {
+				node.IsVolatile = true
+			} else {
+				break
+			}
 		}
 	}
+	return node
+}
 
-	// Function pointer?
-	if this.MatchSymbol(`(`) {
-		// Parse void(*)(args, ...)
-		//            ^
-		//            |
+// matchMemberPointer matches the class and ::* of a pointer to member beginning with token, e.g. the
+// Foo::* of int Foo::*. Otherwise the cursor is put back in front of token.
+func (this *Parser) matchMemberPointer(token *Token) (string, bool) {
+	class := ``
+	next := *token
+	if next.Mtoken == `::` && next.MtokenType == kSymbol {
+		class = `::`
+		if !this.GetToken(&next, false, false) {
+			this.UngetToken(token)
+			return ``, false
+		}
+	}
+	for next.MtokenType == kIdentifier && this.MatchSymbol(`::`) {
+		class += next.Mtoken
 		if this.MatchSymbol(`*`) {
-			var token Token
-			this.GetToken(&token, false, false)
-			if token.Mtoken != `)` || token.MtokenType != kIdentifier || !this.MatchSymbol(`)`) {
-				this.panicf(funcId, `Expected ")"`)
+			return class, true
+		}
+		class += `::`
+		if !this.GetToken(&next, false, false) {
+			break
+		}
+	}
+	this.UngetToken(token)
+	return ``, false
+}
+
+// parseDeclarator applies the declarator at the cursor to the type of a declaration. Declarators are
+// read inside-out: the (*handlers[4])(int) of void (*handlers[4])(int) declares an array of 4 pointers
+// to functions returning void. An abstract declarator such as (*)(int) has no name, a declarator with
+// a name returns its token. The parameter list behind the name is parsed only if allowFunction is set.
+func (this *Parser) parseDeclarator(node *TypeNode, isAbstract bool, allowFunction bool) (*TypeNode, Token) {
+	const funcId = `w5k2rn8e `
+	defer this.enter(funcId)()
+	node = this.parsePointerOperators(node)
+
+	var nameToken Token
+	if isNested, isNamed := this.scanNestedDeclarator(); isNested {
+		if isNamed != isAbstract {
+			// The type the inner declarator applies to is known behind the ), fill it in afterwards
+			this.requireSymbol(`(`)
+			hole := &TypeNode{}
+			var inner *TypeNode
+			inner, nameToken = this.parseDeclarator(hole, isAbstract, true)
+			this.requireSymbol(`)`)
+			return replaceDeclaratorBase(inner, hole, this.parseDeclaratorSuffixes(node, true)), nameToken
+		}
+		// The name of void (*callback)(int) follows the type, leave it to the caller
+		return node, nameToken
+	}
+	if !isAbstract && !this.GetIdentifier(&nameToken) {
+		return node, Token{}
+	}
+	return this.parseDeclaratorSuffixes(node, allowFunction), nameToken
+}
+
+// replaceDeclaratorBase replaces the innermost type hole of the declarator node by base
+func replaceDeclaratorBase(node *TypeNode, hole *TypeNode, base *TypeNode) *TypeNode {
+	if node == hole {
+		return base
+	}
+	switch node.NodeType {
+	case kPointer:
+		node.PointerBase = replaceDeclaratorBase(node.PointerBase, hole, base)
+	case kReference:
+		node.ReferenceBase = replaceDeclaratorBase(node.ReferenceBase, hole, base)
+	case kLReference:
+		node.LReferenceBase = replaceDeclaratorBase(node.LReferenceBase, hole, base)
+	case kMemberPointer:
+		node.MemberPointerBase = replaceDeclaratorBase(node.MemberPointerBase, hole, base)
+	case kArray:
+		node.ArrayBase = replaceDeclaratorBase(node.ArrayBase, hole, base)
+	case kFunction:
+		node.FunctionReturns = replaceDeclaratorBase(node.FunctionReturns, hole, base)
+	}
+	return node
+}
+
+// scanNestedDeclarator tells whether the ( at the cursor encloses a declarator such as the (*callback)
+// of void (*callback)(int) instead of a parameter list, and whether that declarator has a name.
+// The cursor stays in place.
+func (this *Parser) scanNestedDeclarator() (isNested bool, isNamed bool) {
+	mark := Token{MstartPos: this.cursorPos, MstartLine: this.cursorLine}
+	defer this.UngetToken(&mark)
+	if !this.MatchSymbol(`(`) {
+		return false, false
+	}
+	hasOperator := false
+	var token Token
+	for this.GetToken(&token, false, false) {
+		if token.MtokenType == kSymbol && (token.Mtoken == `*` || token.Mtoken == `&` || token.Mtoken == `&&`) {
+			hasOperator = true
+			continue
+		}
+		if token.MtokenType == kIdentifier && (token.Mtoken == `const` || token.Mtoken == `volatile`) {
+			continue
+		}
+		if _, ok := this.matchMemberPointer(&token); ok {
+			hasOperator = true
+			continue
+		}
+		if token.MtokenType == kSymbol && token.Mtoken == `(` {
+			// void (*(*factory)(int))(double), behind an operator the ( may also open a parameter list
+			this.UngetToken(&token)
+			if isNested, isNamed := this.scanNestedDeclarator(); isNested {
+				return true, isNamed
 			}
+			return hasOperator, false
+		}
+		// int (x) declares x, but a parenthesized name is indistinguishable from a parameter list
+		if !hasOperator {
+			return false, false
+		}
+		if token.MtokenType == kIdentifier {
+			return true, true
+		}
+		return token.MtokenType == kSymbol && (token.Mtoken == `)` || token.Mtoken == `[`), false
+	}
+	return false, false
+}
+
+// parseDeclaratorSuffixes parses the array extents or the parameter list following a declarator
+func (this *Parser) parseDeclaratorSuffixes(node *TypeNode, allowFunction bool) *TypeNode {
+	if !allowFunction || !this.MatchSymbol(`(`) {
+		return this.parseArrayExtents(node)
+	}
+	funcNode := NewFunctionNode()
+	funcNode.FunctionReturns = node
+	this.parseFunctionTypeArguments(funcNode)
+	for {
+		if this.MatchIdentifier(`const`) {
+			funcNode.IsConst = true
+		} else if this.MatchIdentifier(`volatile`) {
+			funcNode.IsVolatile = true
+		} else if this.MatchIdentifier(`noexcept`) {
+			funcNode.FunctionIsNoexcept = true
+			if this.MatchSymbol(`(`) {
+				this.scanParenthesized()
+			}
+		} else {
+			break
+		}
+	}
+	return funcNode
+}
+
+// parseFunctionTypeArguments parses the arguments of a function type behind the ( up to the closing )
+func (this *Parser) parseFunctionTypeArguments(funcNode *TypeNode) {
+	const funcId = `u5c8hq3n `
+	if this.MatchSymbol(`)`) {
+		return
+	}
+	for {
+		argument := Argument{}
+		if this.MatchSymbol(`...`) {
+			// C variadic function, e.g. int (*print)(const char *, ...)
+			argument.Type = NewLiteralNode(`...`)
+			funcNode.FunctionArguments = append(funcNode.FunctionArguments, &argument)
+			break
+		}
+		// Parse the type and the optional name
+		var nameToken Token
+		argument.Type, nameToken = this.parseDeclarator(this.parseTypeNode(), false, true)
+		argument.Name = nameToken.Mtoken
+
+		funcNode.FunctionArguments = append(funcNode.FunctionArguments, &argument)
+		if !this.MatchSymbol(`,`) {
+			break
+		}
+	}
+	if !this.MatchSymbol(`)`) {
+		this.panicf(funcId, `Missing ")"`)
+	}
+}
+
+// parseFunctionQualifiers parses what follows the parameter list of a function up to = 0, = default,
+// = delete or the body: cv- and ref-qualifiers, the exception specification, a trailing return type
+// and override and final
+func (this *Parser) parseFunctionQualifiers(function *Function) {
+	const funcId = `p8x3nd7q `
+	for {
+		if !function.IsConst && this.MatchIdentifier(`const`) {
+			function.IsConst = true
+		} else if !function.IsVolatile && this.MatchIdentifier(`volatile`) {
+			function.IsVolatile = true
+		} else {
+			break
 		}
+	}
+	this.debugPrintf(funcId, "function is const %v", function.IsConst)
+	if this.MatchSymbol(`&&`) {
+		function.RefQualifier = `&&`
+	} else if this.MatchSymbol(`&`) {
+		function.RefQualifier = `&`
+	}
 
-		// Parse arguments
-		funcNode := NewFunctionNode()
-		funcNode.FunctionReturns = node
+	// Exception specification
+	if this.MatchIdentifier(`noexcept`) {
+		function.IsNoexcept = true
+		if this.MatchSymbol(`(`) {
+			function.NoexceptExpression = this.scanParenthesized()
+			expression, _ := ParseExpression(function.NoexceptExpression)
+			if value, ok := this.Evaluate(expression); ok && value == 0 {
+				// noexcept(false)
+				function.IsNoexcept = false
+			}
+		}
+	} else if this.MatchIdentifier(`throw`) {
+		// Dynamic exception specification, throw() is the same as noexcept
+		function.HasThrowSpecification = true
+		this.requireSymbol(`(`)
 		if !this.MatchSymbol(`)`) {
 			for {
-				argument := Argument{}
-				argument.Type = this.ParseTypeNode()
-				if argument.Type == nil {
-					return nil
-				}
-				// Get , or name identifier
-				if !this.GetToken(&token, false, false) {
-					this.panicf(funcId, `Unexpected end of file`)
-				}
-
-				// Parse optional name
-				if token.MtokenType == kIdentifier {
-					argument.Name = token.Mtoken
+				if this.MatchSymbol(`...`) {
+					function.ThrowTypes = append(function.ThrowTypes, NewLiteralNode(`...`))
 				} else {
-					this.UngetToken(&token)
+					function.ThrowTypes = append(function.ThrowTypes, this.parseTypeNode())
 				}
-
-				funcNode.FunctionArguments = append(funcNode.FunctionArguments, &argument)
-				if this.MatchSymbol(`,`) {
-					continue
-				} else {
+				if !this.MatchSymbol(`,`) {
 					break
 				}
 			}
-			if !this.MatchSymbol(`)`) {
-				this.panicf(funcId, `Missing ")"`)
+			this.requireSymbol(`)`)
+		}
+		function.IsNoexcept = len(function.ThrowTypes) == 0
+	}
+	this.parseAttributes()
+	function.Attributes = append(function.Attributes, this.takeAttributes()...)
+
+	// auto f() -> int
+	if this.MatchSymbol(`->`) {
+		function.ReturnType = this.parseTypeNode()
+		function.HasTrailingReturnType = true
+	}
+	// void f(T a) requires std::integral<T>;
+	if this.MatchIdentifier(`requires`) {
+		function.TrailingRequiresClause = this.scanRequiresClause()
+	}
+
+	for {
+		if !function.IsOverride && this.MatchIdentifier(`override`) {
+			function.IsOverride = true
+		} else if !function.IsFinal && this.MatchIdentifier(`final`) {
+			function.IsFinal = true
+		} else {
+			break
+		}
+	}
+	// void exit() __attribute__((noreturn));
+	this.parseAttributes()
+	function.Attributes = append(function.Attributes, this.takeAttributes()...)
+	function.IsNodiscard = function.Attribute(`nodiscard`) != nil
+}
+
+// scanParenthesized returns the source behind the ( at the cursor up to the matching ) and skips both
+func (this *Parser) scanParenthesized() string {
+	const funcId = `t1g6we0r `
+	startPos := this.cursorPos
+	depth := 1
+	var token Token
+	for this.GetToken(&token, false, false) {
+		if token.MtokenType != kSymbol {
+			continue
+		}
+		switch token.Mtoken {
+		case `(`:
+			depth++
+		case `)`:
+			depth--
+			if depth == 0 {
+				return strings.TrimSpace(string(this.input[startPos:token.MstartPos]))
 			}
 		}
+	}
+	this.panicf(funcId, `Missing ")"`)
+	return ``
+}
+
+// isConstructor tells whether the next tokens are the name of the class followed by (. Inside of its class
+// that is the name of the enclosing class, outside of it the last name of the qualifier, e.g. Foo::Foo(
+func (this *Parser) isConstructor(qualifier string) bool {
+	className := qualifier
+	if qualifier == `` {
+		scope := this.topScope()
+		if scope.scopeType != kClass {
+			return false
+		}
+		className = scope.name
+	}
+	if idx := strings.LastIndex(className, `::`); idx >= 0 {
+		className = className[idx+2:]
+	}
+	var token Token
+	if !this.GetIdentifier(&token) {
+		return false
+	}
+	isConstructor := token.Mtoken == className && this.MatchSymbol(`(`)
+	this.UngetToken(&token)
+	return isConstructor
+}
+
+// isUntypedFunction tells whether a constructor, a destructor or a conversion operator follows, they have no type.
+// Outside of their class they are qualified, e.g. Foo::Foo(, A<T>::~A( or Foo::operator bool
+func (this *Parser) isUntypedFunction() bool {
+	mark := Token{MstartPos: this.cursorPos, MstartLine: this.cursorLine}
+	defer this.UngetToken(&mark)
+	qualifier := this.parseNestedNameSpecifier()
+	return this.MatchSymbol(`~`) || this.MatchIdentifier(`operator`) || this.isConstructor(qualifier)
+}
+
+// parseNestedNameSpecifier parses the qualifier in front of a name without the trailing ::,
+// e.g. A::B of A<T>::B::f. The template arguments are not part of the qualifier.
+func (this *Parser) parseNestedNameSpecifier() string {
+	qualifier := ``
+	for {
+		mark := Token{MstartPos: this.cursorPos, MstartLine: this.cursorLine}
+		var token Token
+		if !this.GetIdentifier(&token) || token.Mtoken == `operator` {
+			this.UngetToken(&mark)
+			return qualifier
+		}
+		this.parseTemplateArgumentList()
+		if !this.MatchSymbol(`::`) {
+			this.UngetToken(&mark)
+			return qualifier
+		}
+		qualifier = joinQualifiedName(qualifier, token.Mtoken)
+	}
+}
 
-		node = funcNode
+// isQualifiedFunction tells whether the name of a member defined outside of its class follows,
+// e.g. A<T>::f( or A::operator==
+func (this *Parser) isQualifiedFunction() bool {
+	mark := Token{MstartPos: this.cursorPos, MstartLine: this.cursorLine}
+	defer this.UngetToken(&mark)
+	if this.parseNestedNameSpecifier() == `` {
+		return false
 	}
+	if this.MatchIdentifier(`operator`) {
+		return true
+	}
+	var token Token
+	return this.GetIdentifier(&token) && this.MatchSymbol(`(`)
+}
 
-	// This stuff refers to the top node
-	node.IsVolatile = isVolatile
-	node.IsMutable = isMutable
+// isFunctionSpecialization tells whether the name at the cursor is followed by template arguments
+// and a parameter list, e.g. f<int>(int), without moving the cursor
+func (this *Parser) isFunctionSpecialization() bool {
+	mark := Token{MstartPos: this.cursorPos, MstartLine: this.cursorLine}
+	defer this.UngetToken(&mark)
+	var token Token
+	return this.GetIdentifier(&token) && this.skipTemplateArgumentList() && this.MatchSymbol(`(`)
+}
 
-	return node
+// operatorSymbols are the operators which can be overloaded
+var operatorSymbols = map[string]bool{
+	`+`: true, `-`: true, `*`: true, `/`: true, `%`: true, `^`: true, `&`: true, `|`: true, `~`: true,
+	`!`: true, `=`: true, `<`: true, `>`: true, `+=`: true, `-=`: true, `*=`: true, `/=`: true, `%=`: true,
+	`^=`: true, `&=`: true, `|=`: true, `<<`: true, `>>`: true, `<<=`: true, `>>=`: true, `==`: true,
+	`!=`: true, `<=`: true, `>=`: true, `&&`: true, `||`: true, `++`: true, `--`: true, `,`: true,
+	`->*`: true, `->`: true, `<=>`: true,
+}
+
+// operatorName returns the name of the operator function, a keyword is separated by a space like the
+// type of a conversion operator, e.g. operator== and operator new[]
+func operatorName(symbol string) string {
+	if isAlpha(symbol[0]) {
+		return `operator ` + symbol
+	}
+	return `operator` + symbol
+}
+
+// parseOperatorSymbol parses the operator behind the operator keyword, e.g. the == of operator==
+func (this *Parser) parseOperatorSymbol() string {
+	const funcId = `e2n7ub5k `
+	var token Token
+	if !this.GetToken(&token, false, false) {
+		this.panicf(funcId, `Expected operator`)
+	}
+	switch {
+	case token.Mtoken == `(`:
+		this.requireSymbol(`)`)
+		return `()`
+	case token.Mtoken == `[`:
+		this.requireSymbol(`]`)
+		return `[]`
+	case token.Mtoken == `new` || token.Mtoken == `delete`:
+		if this.MatchSymbol(`[`) {
+			this.requireSymbol(`]`)
+			return token.Mtoken + `[]`
+		}
+		return token.Mtoken
+	case token.Mtoken == `co_await`:
+		return token.Mtoken
+	case token.MtokenType == kConst && token.MconstType == kString && token.Mtoken == ``:
+		// User-defined literal, e.g. operator""_km
+		var suffix Token
+		if !this.GetIdentifier(&suffix) {
+			this.panicf(funcId, `Expected literal suffix`)
+		}
+		return `""` + suffix.Mtoken
+	case token.MtokenType == kSymbol:
+		symbol := token.Mtoken
+		for {
+			// The tokenizer splits some operators, e.g. <<= or ->*
+			var next Token
+			if !this.GetToken(&next, false, false) {
+				break
+			}
+			if next.MtokenType != kSymbol || next.MstartPos != token.MstartPos+len(symbol) || !operatorSymbols[symbol+next.Mtoken] {
+				this.UngetToken(&next)
+				break
+			}
+			symbol += next.Mtoken
+		}
+		if operatorSymbols[symbol] {
+			return symbol
+		}
+	}
+	this.UngetToken(&token)
+	this.panicf(funcId, `Unknown operator %v`, token.Mtoken)
+	return ``
+}
+
+// skipInitializerList skips the member initializers behind the : of a constructor up to its body
+func (this *Parser) skipInitializerList() {
+	const funcId = `s9d4fj0m `
+	depth := 0
+	closed := false // the previous token closed an initializer
+	var token Token
+	for this.GetToken(&token, false, false) {
+		switch token.Mtoken {
+		case `{`:
+			if depth == 0 && closed {
+				this.UngetToken(&token)
+				return
+			}
+			depth++
+		case `(`:
+			depth++
+		case `}`, `)`:
+			depth--
+		case `;`:
+			if depth == 0 {
+				this.panicf(funcId, `Missing body of the constructor`)
+			}
+		}
+		closed = depth == 0 && (token.Mtoken == `}` || token.Mtoken == `)`)
+	}
+	this.panicf(funcId, `Unexpected end of file`)
 }
 
-func (this *Parser) ParseTypeNodeDeclarator() string {
+func (this *Parser) parseTypeNodeDeclarator() string {
 	const funcId = `grns8napbd `
 	// Skip optional forward declaration specifier
 	this.MatchIdentifier(`class`)
 	this.MatchIdentifier(`struct`)
+	this.MatchIdentifier(`union`)
 	this.MatchIdentifier(`typename`)
 
 	// Parse a type name
@@ -1,6 +1,8 @@
 package ymdCppHeaderParser
 
 import (
+	"errors"
+	"strings"
 	"testing"
 )
 
@@ -37,7 +39,8 @@ func TestParseFile(t *testing.T) {
 
 func TestParser_ParseTypeNode(t *testing.T) {
 	p := NewParser([]byte(`void test1(int , int);`))
-	node := p.ParseTypeNode()
+	node, err := p.ParseTypeNode()
+	assert(err == nil, err)
 	assert(node.NodeType == kLiteral)
 	assert(node.LiteralName == `void`)
 	assert(p.cursorPos == 5, p.cursorPos)
@@ -57,8 +60,8 @@ func TestParser_ParseAccessControl(t *testing.T) {
 
 func TestParser_ParseFunction(t *testing.T) {
 	p := NewParser([]byte(`user::bool ProtectedFunction(std::map<int, std::string> ) const ;`))
-	ok := p.ParseFunction()
-	assert(ok)
+	err := p.ParseFunction()
+	assert(err == nil, err)
 }
 
 func TestParser_ParseClass(t *testing.T) {
@@ -81,8 +84,8 @@ func TestParser_ParseDeclaration(t *testing.T) {
 	var token Token
 	ok1 := p.GetToken(&token, false, false)
 	assert(ok1)
-	ok2 := p.ParseDeclaration(&token)
-	assert(ok2)
+	err := p.ParseDeclaration(&token)
+	assert(err == nil, err)
 }
 
 func TestParser_ParseDirective(t *testing.T) {
@@ -90,3 +93,972 @@ func TestParser_ParseDirective(t *testing.T) {
 	p.ParseDirective()
 }
 
+
+func TestParser_ParseAllModel(t *testing.T) {
+	p := NewParser([]byte(content))
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	assert(len(file.Includes) == 1 && file.Includes[0].Path == `vector` && file.Includes[0].IsSystem)
+	assert(len(file.Namespaces) == 1 && file.Namespaces[0].Name == `test`)
+
+	ns := file.Namespaces[0]
+	assert(len(ns.Classes) == 1)
+	class := ns.Classes[0]
+	assert(class.Name == `Foo` && class.Scope == `test` && class.QualifiedName() == `test::Foo`)
+	assert(class.Line == 8, class.Line)
+	assert(len(class.Bases) == 1 && class.Bases[0].Name == `Bar` && class.Bases[0].Access == kPublic)
+
+	assert(len(class.Functions) == 2)
+	f := class.Functions[0]
+	assert(f.Name == `ProtectedFunction` && f.Scope == `test::Foo` && f.Access == kProtected && f.IsConst)
+	assert(f.ReturnType.LiteralName == `bool`)
+	assert(len(f.Arguments) == 1 && f.Arguments[0].Name == `args`)
+	assert(f.Arguments[0].Type.TemplateName == `std::vector`)
+	assert(class.Functions[1].Name == `inProgress` && class.Functions[1].IsVirtual && class.Functions[1].IsPure)
+
+	assert(len(class.Enums) == 1 && class.Enums[0].Name == `Enum` && class.Enums[0].Access == kPublic)
+	assert(len(class.Fields) == 1)
+	assert(class.Fields[0].Name == `ThisIsAProperty` && class.Fields[0].Type.LiteralName == `int`)
+	assert(class.Fields[0].Line == 23, class.Fields[0].Line)
+}
+
+func TestParser_ParseFunctionArguments(t *testing.T) {
+	p := NewParser([]byte(`void f(int a, int = 3, std::string c = std::string("x", 1));`))
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	assert(len(file.Functions) == 1)
+	args := file.Functions[0].Arguments
+	assert(len(args) == 3)
+	assert(args[0].Name == `a` && args[0].DefaultValue == ``)
+	assert(args[1].Name == `` && args[1].DefaultValue == `3`)
+	assert(args[2].Name == `c` && args[2].DefaultValue == `std::string("x", 1)`, args[2].DefaultValue)
+}
+
+func TestNewParserWithOptions(t *testing.T) {
+	p := NewParserWithOptions([]byte(`TCLASS(Serializable, Name="x") class Foo { TPROPERTY() int a; int b; };`), ParserOptions{
+		ClassNameMacro:    `TCLASS`,
+		PropertyNameMacro: `TPROPERTY`,
+	})
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	assert(len(file.Classes) == 1)
+	class := file.Classes[0]
+	assert(class.Macro == `TCLASS` && len(class.Meta.Entries) == 2)
+	assert(len(class.Fields) == 2)
+	assert(class.Fields[0].Name == `a` && class.Fields[0].Macro == `TPROPERTY`)
+	assert(class.Fields[1].Name == `b` && class.Fields[1].Macro == ``)
+
+	p = NewParserWithOptions([]byte(`TCLASS() class Foo { TPROPERTY() int a; int b; }; class Bar {};`), ParserOptions{
+		ClassNameMacro:    `TCLASS`,
+		PropertyNameMacro: `TPROPERTY`,
+		AnnotatedOnly:     true,
+	})
+	file, err = p.ParseAll()
+	assert(err == nil, err)
+	assert(len(file.Classes) == 1 && file.Classes[0].Name == `Foo`)
+	assert(len(file.Classes[0].Fields) == 1 && file.Classes[0].Fields[0].Name == `a`)
+}
+
+func TestParser_ParseMetaSequence(t *testing.T) {
+	p := NewParser([]byte(`(Range(Min=0, Max=10.5), Tooltip="x",
+	Hidden, Enabled=true, Category=Game::Play, Tags={A, "b", -1})`))
+	p.RequireSymbol(`(`)
+	meta, err := p.ParseMetaSequence()
+	assert(err == nil, err)
+	assert(meta.MetaType == kMetaObject && len(meta.Entries) == 6)
+
+	value, ok := meta.Lookup(`Range`)
+	assert(ok && value.MetaType == kMetaObject && value.Line == 1 && value.Column == 8, marshalJson(value))
+	min, ok := value.Lookup(`Min`)
+	assert(ok && min.ConstType == kInt64 && min.Int64Const == 0)
+	max, ok := value.Lookup(`Max`)
+	assert(ok && max.ConstType == kFloat64 && max.Float64Const == 10.5)
+
+	value, ok = meta.Lookup(`Tooltip`)
+	assert(ok && value.ConstType == kString && value.StringConst == `x`)
+	value, ok = meta.Lookup(`Hidden`)
+	assert(ok && value == nil)
+	assert(meta.Entries[2].Line == 2 && meta.Entries[2].Column == 2, marshalJson(meta.Entries[2]))
+	value, ok = meta.Lookup(`Enabled`)
+	assert(ok && value.ConstType == kBoolean && value.BoolConst)
+	value, ok = meta.Lookup(`Category`)
+	assert(ok && value.MetaType == kMetaIdentifier && value.Identifier == `Game::Play`)
+	value, ok = meta.Lookup(`Tags`)
+	assert(ok && value.MetaType == kMetaList && len(value.Items) == 3)
+	assert(value.Items[0].Identifier == `A` && value.Items[1].StringConst == `b` && value.Items[2].Int64Const == -1)
+	_, ok = meta.Lookup(`Missing`)
+	assert(!ok)
+}
+
+func TestParser_ParseAllError(t *testing.T) {
+	p := NewParser([]byte("namespace a {\n  class Foo {\n    int ;\n  };\n}"))
+	p.FileName = `foo.h`
+	file, err := p.ParseAll()
+	assert(file != nil && len(file.Namespaces) == 1)
+
+	var parseError *ParseError
+	assert(errors.As(err, &parseError), err)
+	assert(parseError.File == `foo.h` && parseError.Line == 3 && parseError.Column == 9, marshalJson(parseError))
+	assert(parseError.Code == `8w3c6jsa` && parseError.Message == `Expected a property or method name`)
+	assert(err.Error() == `foo.h:3:9: Expected a property or method name (8w3c6jsa)`, err.Error())
+}
+
+func TestParser_ParseAllUnterminated(t *testing.T) {
+	for _, input := range []string{
+		`class Foo {`,
+		`namespace a { int b;`,
+		`enum E { A = `,
+		`void f(int a`,
+		`TCLASS(A=`,
+		`std::vector<std::map<int`,
+		`TCLASS(A=0x) class B {};`,
+		strings.Repeat(`a<`, 10000),
+	} {
+		p := NewParserWithOptions([]byte(input), ParserOptions{ClassNameMacro: `TCLASS`})
+		_, err := p.ParseAll()
+		var parseError *ParseError
+		assert(errors.As(err, &parseError), input)
+		assert(parseError.Code != `internal`, input, parseError.Message)
+	}
+}
+
+func FuzzParser_ParseAll(f *testing.F) {
+	f.Add(content)
+	f.Add(`TCLASS(Range(Min=0, Max=10), Tags={A, "b"}) class A : public B { TFUNC() void f(int a = 1) const; };`)
+	f.Add(`enum class E : int { A = 1 << 2, B }; int *p; #define X 1`)
+	f.Add("/// doc\nint a; // trailing\n/* block */ //")
+	f.Add("template <typename T, int N = 2, template <class> class C> struct A<T*, (N > 1)> { template <> void f<>(std::array<int, sizeof(T)>); };")
+	f.Add("struct F { F() : a(1), b{2} {} explicit operator bool() const; ~F() = default; F &operator<<=(int); };")
+	f.Add("#define N (1 << 2)\nenum E { A = N ? sizeof(int) : (unsigned char)-1, B = static_cast<int>(f(A, 2)) };")
+	f.Add("using namespace std; typedef void (*F)(int a); template <class T> using V = std::vector<T>; struct D : B { using B::B, B::operator=; };")
+	f.Add("struct V { union { int i; struct { short s; }; }; } v, *pv; typedef struct { int x; } P, *PP; struct V *next;")
+	f.Add("namespace { int a; } namespace a::b { inline namespace v1 { enum E { X = 1 }; } } namespace fs = a::b; namespace c::inline d {}")
+	f.Add("extern \"C\" { int f(); extern int g; } extern \"C\" void h(); extern template class A<int>;")
+	f.Add("struct A { [[nodiscard]] virtual auto f() const volatile && noexcept(1) -> int override final = 0; void g() throw(int, ...); friend bool operator!=(A, A); };")
+	f.Add("[[nodiscard]] int f([[maybe_unused]] int a) __attribute__((pure)); struct alignas(8) [[x]] S { int v [[gnu::aligned(4)]]; }; enum class [[y]] E { A [[z]] };")
+	f.Add("struct F { int a[4][N], b : 3 = 1, *c{nullptr}; unsigned : 0; std::string s{\"x\"}; static constexpr int k = 2 * 3; } f[2];")
+	f.Add("typedef void (*Cb)(int), H(int); void (*(*f)(int))(double); int (*g(int))[4]; int Foo::*m; void (Foo::*p)(int) const; int ((*q))(int, ...);")
+	f.Add("class API W final : public B<T>, virtual protected ::ns::M, private virtual C... {}; struct API final;")
+	f.Add("class A { friend class B; friend C<int>; template <class T> friend struct D; friend bool operator<(A, A); static_assert(f<1, 2>(), \"m\" \"n\"); }; class A::E { }; _Static_assert(1);")
+	f.Add("template <class T> concept C = requires (T a) { a + a; }; template <C T, std::integral U> requires C<T> && (U(1) > 0) struct S { explicit(!C<T>) S(T); consteval auto operator<=>(const S &) const = default; void f(C auto x) requires true; static constinit int n; };")
+	f.Add("class MYLIB_API Foo { int x; }; struct MYLIB_API Bar final : Foo {}; struct stat info; class A final {};")
+	f.Add("auto f(int a, int b) -> decltype(a * b); decltype(x) y; decltype(auto) g(); const decltype(f(1, 2)) *p;")
+	f.Add("inline Foo::Foo() : a(0) {} Foo::~Foo() {} template <class T> A<T>::~A() {} ns::Bar::operator bool() const; class Outer::Inner { Inner(); };")
+	f.Add("template <class T> template <class U> void A<T>::f(U u) {} template <class T> template <int N> struct A<T>::B<N>::C {}; bool A::operator==(const A &) const;")
+	f.Add("enum E { A = ',', B = L'\\x41' }; void f(char c = ')', int a = 1 << 3, char d = '\\'');")
+	f.Add("long unsigned int long a; const unsigned char* b; short int f(signed, long double); int long long c = sizeof(unsigned);")
+	f.Fuzz(func(t *testing.T, input string) {
+		for _, recover := range []bool{false, true} {
+			p := NewParserWithOptions([]byte(input), ParserOptions{
+				ClassNameMacro:    `TCLASS`,
+				EnumNameMacro:     `TENUM`,
+				FunctionNameMacro: `TFUNC`,
+				PropertyNameMacro: `TPROPERTY`,
+				Recover:           recover,
+				ExportMacros:      []string{`API`},
+			})
+			_, err := p.ParseAll()
+			var parseError *ParseError
+			if errors.As(err, &parseError) && parseError.Code == `internal` {
+				t.Fatal(parseError)
+			}
+			for _, one := range p.Diagnostics() {
+				if one.Code == `internal` {
+					t.Fatal(one)
+				}
+			}
+		}
+	})
+}
+
+func TestParser_ParseAllRecover(t *testing.T) {
+	p := NewParserWithOptions([]byte(`int a;
+class Foo {
+	int ;
+	void f();
+	@ g() { return; }
+	int b;
+};
+} int c;
+namespace n { enum { A }; int d; }
+int e;`), ParserOptions{Recover: true})
+	file, err := p.ParseAll()
+
+	var diagnostics Diagnostics
+	assert(errors.As(err, &diagnostics) && len(diagnostics) == 3, err)
+	var parseError *ParseError
+	assert(errors.As(err, &parseError) && parseError.Line == 3)
+	first := diagnostics[0]
+	assert(first.SkippedStartLine == 3 && first.SkippedStartColumn == 2, marshalJson(first))
+	assert(first.SkippedEndLine == 3 && first.SkippedEndColumn == 7, marshalJson(first))
+	assert(diagnostics[1].Line == 5 && diagnostics[1].SkippedEndLine == 5)
+	assert(diagnostics[2].Line == 8 && diagnostics[2].SkippedEndColumn == 2, marshalJson(diagnostics[2]))
+
+	assert(len(file.Fields) == 3)
+	assert(file.Fields[0].Name == `a` && file.Fields[1].Name == `c` && file.Fields[2].Name == `e`)
+	assert(len(file.Classes) == 1)
+	class := file.Classes[0]
+	assert(len(class.Functions) == 1 && class.Functions[0].Name == `f`)
+	assert(len(class.Fields) == 1 && class.Fields[0].Name == `b`)
+	assert(len(file.Namespaces) == 1 && len(file.Namespaces[0].Fields) == 1)
+	assert(file.Namespaces[0].Fields[0].QualifiedName() == `n::d`)
+	assert(len(file.Namespaces[0].Enums) == 1 && file.Namespaces[0].Enums[0].Name == ``)
+	assert(file.Namespaces[0].Enums[0].Enumerators[0].Name == `A`)
+	assert(len(p.Diagnostics()) == 3)
+}
+
+func TestParser_ParseUnknownDeclarations(t *testing.T) {
+	_, err := NewParser([]byte("int a;\nDECLARE_THING Foo bar;\nint b;")).ParseAll()
+	var parseError *ParseError
+	assert(errors.As(err, &parseError) && parseError.Line == 2 && parseError.Column == 19, err)
+	assert(parseError.Message == `Unexpected bar behind Foo`, parseError.Message)
+
+	p := NewParserWithOptions([]byte(`int a;
+DECLARE_THING Foo bar;
+int A::count = 0;
+template <> int size<char> = 1;
+template <class T> int B<T>::count = 0;
+void f();`), ParserOptions{Recover: true})
+	file, err := p.ParseAll()
+	assert(len(p.Diagnostics()) == 1 && p.Diagnostics()[0].Line == 2, err)
+	// The definitions of declared variables are skipped
+	assert(len(file.Fields) == 1 && file.Fields[0].Name == `a`)
+	assert(len(file.Functions) == 1 && file.Functions[0].Name == `f` && !file.Functions[0].IsTemplate)
+}
+
+func TestParser_ParseAllComments(t *testing.T) {
+	p := NewParser([]byte(`// License header
+
+/// The engine
+/// does things
+class Engine {
+public:
+	/**
+	 * Starts the engine.
+	 */
+	void Start(); // not thread safe
+	int a; ///< speed
+	// unrelated
+
+	int b;
+	int c; /* c */ int d;
+};
+
+enum Color {
+	Red, ///< red
+	/// green
+	Green = 2, // two
+	Blue // last
+};
+namespace ns {
+} // namespace ns
+`))
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	class := file.Classes[0]
+	assert(class.Comment == "The engine\ndoes things", class.Comment)
+	assert(class.Functions[0].Comment == `Starts the engine.`, class.Functions[0].Comment)
+	assert(class.Functions[0].TrailingComment == `not thread safe`)
+	fields := class.Fields
+	assert(len(fields) == 4)
+	assert(fields[0].Name == `a` && fields[0].Comment == `` && fields[0].TrailingComment == `speed`)
+	assert(fields[1].Name == `b` && fields[1].Comment == ``)
+	assert(fields[2].Name == `c` && fields[2].TrailingComment == `c`)
+	assert(fields[3].Name == `d` && fields[3].Comment == ``)
+
+	enumerators := file.Enums[0].Enumerators
+	assert(enumerators[0].TrailingComment == `red` && enumerators[0].Comment == ``)
+	assert(enumerators[1].Comment == `green` && enumerators[1].TrailingComment == `two`)
+	assert(enumerators[2].TrailingComment == `last`)
+	assert(file.Namespaces[0].TrailingComment == `namespace ns`)
+}
+
+func TestParser_ParseAllAnnotatedComment(t *testing.T) {
+	p := NewParserWithOptions([]byte(`
+/// Documented
+TCLASS()
+class Foo {};
+`), ParserOptions{ClassNameMacro: `TCLASS`})
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	assert(file.Classes[0].Comment == `Documented`, file.Classes[0].Comment)
+}
+
+func TestParser_ParseEnumValues(t *testing.T) {
+	p := NewParser([]byte(`
+enum Flags : unsigned int { None, Read = 1 << 0, Write = 1 << 1, All = Read | Write, Next };
+namespace ns {
+enum class Color : std::uint8_t { Red = 5, Green, Blue = Flags::All + Red * 2 };
+}
+enum Other { First = ns::Color::Green, Unknown = SOME_MACRO, AfterUnknown };
+enum class Opaque : int;
+enum { Anonymous = Flags::Next + 1 };
+enum : long { Sized };
+int size[Anonymous];
+`))
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	assert(len(file.Enums) == 4)
+	flags := file.Enums[0]
+	assert(!flags.IsClass && flags.BaseType == `unsigned int`, flags.BaseType)
+	values := []int64{0, 1, 2, 3, 4}
+	for i, one := range flags.Enumerators {
+		assert(one.HasValue && one.Value == values[i], one.Name, one.Value)
+	}
+	assert(flags.Enumerators[3].Expression == `Read | Write`)
+
+	color := file.Namespaces[0].Enums[0]
+	assert(color.IsClass && color.BaseType == `std::uint8_t`)
+	assert(color.Enumerators[1].Value == 6 && color.Enumerators[2].Value == 13, color.Enumerators[2].Value)
+
+	other := file.Enums[1]
+	assert(other.Enumerators[0].HasValue && other.Enumerators[0].Value == 6)
+	assert(!other.Enumerators[1].HasValue && !other.Enumerators[2].HasValue)
+
+	anonymous := file.Enums[2]
+	assert(anonymous.Name == `` && anonymous.Enumerators[0].Name == `Anonymous` && anonymous.Enumerators[0].Value == 5)
+	assert(file.Enums[3].Name == `` && file.Enums[3].BaseType == `long`)
+	size, ok := p.Evaluate(file.Fields[0].Type.ParsedArraySize)
+	assert(ok && size == 5, marshalJson(file.Fields[0]))
+}
+
+func TestParser_ParseSpecialMembers(t *testing.T) {
+	p := NewParser([]byte(`
+class Foo {
+public:
+	Foo();
+	explicit Foo(int a) : m_a(a), m_b{a, 2} { init(); }
+	Foo(const Foo &) = delete;
+	virtual ~Foo() = default;
+	bool operator==(const Foo &other) const;
+	int operator()(int) const;
+	explicit operator bool() const;
+	operator const char *() const;
+	void *operator new[](size_t size);
+	Foo &operator<<=(int shift);
+	void *operator new(size_t size);
+	void operator delete(void *p) noexcept;
+	void operator delete[](void *p);
+	Foo *operator->*(int) { return this; }
+	Foo &operator=(Foo &&other);
+	int m_a;
+};
+double operator""_km(double);
+`))
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	functions := file.Classes[0].Functions
+	assert(len(functions) == 15, len(functions))
+	assert(functions[0].FunctionKind == kConstructor && functions[0].Name == `Foo` && functions[0].ReturnType == nil)
+	assert(functions[1].FunctionKind == kConstructor && functions[1].IsExplicit && functions[1].Arguments[0].Name == `a`)
+	assert(functions[2].IsDeleted && len(functions[2].Arguments) == 1)
+	assert(functions[3].FunctionKind == kDestructor && functions[3].Name == `~Foo`)
+	assert(functions[3].IsVirtual && functions[3].IsDefault)
+	assert(functions[4].FunctionKind == kOperator && functions[4].Operator == `==` && functions[4].IsConst)
+	assert(functions[4].Name == `operator==` && functions[4].ReturnType.LiteralName == `bool`)
+	assert(functions[5].Operator == `()` && len(functions[5].Arguments) == 1)
+	assert(functions[6].FunctionKind == kConversionOperator && functions[6].Name == `operator bool`)
+	assert(functions[6].ReturnType.LiteralName == `bool` && functions[6].IsExplicit && functions[6].IsConst)
+	assert(functions[7].Name == `operator const char *` && functions[7].ReturnType.NodeType == kPointer)
+	assert(functions[8].Operator == `new[]` && functions[8].Name == `operator new[]`)
+	assert(functions[9].Operator == `<<=` && functions[9].Name == `operator<<=`)
+	assert(functions[10].Operator == `new` && functions[10].Name == `operator new`)
+	assert(functions[11].Operator == `delete` && functions[11].Name == `operator delete` && functions[11].IsNoexcept)
+	assert(functions[12].Operator == `delete[]` && functions[12].Name == `operator delete[]`)
+	assert(functions[13].Operator == `->*`)
+	assert(functions[14].Operator == `=` && functions[14].Arguments[0].Type.NodeType == kLReference)
+	assert(len(file.Classes[0].Fields) == 1)
+	assert(file.Functions[0].Operator == `""_km`)
+}
+
+func TestParser_ParseSpecialMembersOutOfClass(t *testing.T) {
+	p := NewParser([]byte(`
+inline Foo::Foo() : m_a(0) {}
+Foo::~Foo() {}
+template <class T> A<T>::A(const A &other) = default;
+template <class T> A<T>::~A() noexcept {}
+ns::Bar::operator bool() const { return true; }
+Foo::Bar Foo::make();
+class Outer::Inner { Inner(); };
+`))
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	functions := file.Functions
+	assert(len(functions) == 6, len(functions))
+	assert(functions[0].FunctionKind == kConstructor && functions[0].QualifiedName() == `Foo::Foo` && functions[0].IsInline)
+	assert(functions[0].ReturnType == nil)
+	assert(functions[1].FunctionKind == kDestructor && functions[1].QualifiedName() == `Foo::~Foo`)
+	assert(functions[2].FunctionKind == kConstructor && functions[2].QualifiedName() == `A::A` && functions[2].IsTemplate)
+	assert(functions[2].IsDefault && len(functions[2].Arguments) == 1)
+	assert(functions[3].FunctionKind == kDestructor && functions[3].QualifiedName() == `A::~A` && functions[3].IsNoexcept)
+	assert(functions[4].FunctionKind == kConversionOperator && functions[4].QualifiedName() == `ns::Bar::operator bool`)
+	assert(functions[5].FunctionKind == kOrdinaryFunction && functions[5].QualifiedName() == `Foo::make`)
+	assert(functions[5].ReturnType.LiteralName == `Foo::Bar`)
+
+	inner := file.Classes[0].Functions
+	assert(len(inner) == 1 && inner[0].FunctionKind == kConstructor && inner[0].QualifiedName() == `Outer::Inner::Inner`)
+}
+
+func TestParser_ParseUnionsAndDeclarators(t *testing.T) {
+	p := NewParser([]byte(`
+union U { int i; float f; };
+
+struct Variant {
+	int type;
+	union {
+		int i;
+		struct { short lo; short hi; };
+		struct { float x; } point;
+	};
+};
+
+typedef struct {
+	int x, y;
+} Point, *PPoint;
+
+struct Node {
+	struct Node *next;
+} g_node, *g_pnode;
+
+typedef struct Tag { int t; } Tag;
+template <typename T> struct A<T, (1 > 0)> {};
+`))
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	assert(len(file.Classes) == 6, len(file.Classes))
+
+	u := file.Classes[0]
+	assert(u.Name == `U` && u.IsUnion && !u.IsStruct && len(u.Fields) == 2)
+	assert(u.Fields[0].Access == kPublic)
+
+	variant := file.Classes[1]
+	anonymous := variant.Classes[0]
+	assert(anonymous.IsAnonymous && anonymous.IsUnion && anonymous.Name == ``)
+	assert(len(anonymous.Classes) == 2 && anonymous.Classes[0].IsAnonymous)
+	assert(!anonymous.Classes[1].IsAnonymous && anonymous.Classes[1].Name == ``)
+	names := []string{}
+	for _, one := range variant.Fields {
+		names = append(names, one.QualifiedName())
+	}
+	// The fields of the anonymous aggregates are promoted, the named point stays a field of the union
+	assert(strings.Join(names, ` `) == `Variant::type Variant::i Variant::lo Variant::hi Variant::point`, names)
+
+	point := file.Classes[2]
+	assert(point.Name == `Point` && point.IsStruct && len(point.Fields) == 2 && point.Fields[1].Name == `y`)
+	assert(len(file.TypeAliases) == 3)
+	assert(file.TypeAliases[0].Name == `Point` && file.TypeAliases[0].Type.LiteralName == `Point`)
+	assert(file.TypeAliases[1].Name == `PPoint` && file.TypeAliases[1].Type.PointerBase.LiteralName == `Point`)
+	assert(file.TypeAliases[2].Name == `Tag` && file.Classes[4].Name == `Tag`)
+
+	node := file.Classes[3]
+	assert(len(node.Fields) == 1 && node.Fields[0].Type.PointerBase.LiteralName == `Node`)
+	assert(len(file.Fields) == 2)
+	assert(file.Fields[0].Name == `g_node` && file.Fields[0].Type.LiteralName == `Node`)
+	assert(file.Fields[1].Name == `g_pnode` && file.Fields[1].Type.NodeType == kPointer)
+	assert(file.Classes[5].IsSpecialization())
+
+	json := MarshalUpstreamJson(file, ``)
+	assert(strings.Count(json, `"name":"i"`) == 2, json)
+}
+
+func TestParser_ParseNamespaces(t *testing.T) {
+	p := NewParser([]byte(`
+namespace {
+	int hidden;
+}
+namespace a::b::c {
+	class Foo {};
+}
+namespace std {
+	inline namespace __1 {
+		class string {};
+		enum Flag { kOne = 1 };
+	}
+	namespace experimental::inline v2 {
+		int g;
+	}
+}
+namespace fs = std::filesystem;
+enum E { kTwo = std::Flag::kOne + 1 };
+`))
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	assert(len(file.Namespaces) == 3 && len(file.NamespaceAliases) == 1)
+
+	anonymous := file.Namespaces[0]
+	assert(anonymous.Name == `` && anonymous.Fields[0].QualifiedName() == `hidden`)
+
+	a := file.Namespaces[1]
+	c := a.Namespaces[0].Namespaces[0]
+	assert(a.Name == `a` && c.Name == `c` && c.Scope == `a::b`)
+	assert(c.Classes[0].QualifiedName() == `a::b::c::Foo`)
+
+	std := file.Namespaces[2]
+	inline := std.Namespaces[0]
+	assert(inline.IsInline && !std.IsInline)
+	stringClass := inline.Classes[0]
+	assert(stringClass.QualifiedName() == `std::__1::string` && stringClass.VisibleQualifiedName() == `std::string`)
+	assert(c.Classes[0].VisibleQualifiedName() == `a::b::c::Foo`)
+	v2 := std.Namespaces[1].Namespaces[0]
+	assert(v2.IsInline && v2.Fields[0].VisibleQualifiedName() == `std::experimental::g`)
+
+	alias := file.NamespaceAliases[0]
+	assert(alias.Name == `fs` && alias.Target == `std::filesystem`)
+	assert(file.Enums[0].Enumerators[0].HasValue && file.Enums[0].Enumerators[0].Value == 2)
+}
+
+func TestParser_ParseLinkageSpecification(t *testing.T) {
+	p := NewParser([]byte(`
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+int init(const char *name);
+extern int counter;
+struct Handle { int fd; void close(); };
+
+#ifdef __cplusplus
+}
+#endif
+
+extern "C" void single();
+extern "C++" int cpp;
+namespace ns { extern "C" { double value; } }
+void mangled();
+extern template class std::vector<int>;
+extern "C" {
+	void before();
+	extern "C++" { int overloaded(); }
+	void after();
+}
+`))
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	assert(len(file.Functions) == 6 && len(file.Fields) == 2)
+	assert(file.Functions[0].Name == `init` && file.Functions[0].Linkage == `C`)
+	assert(file.Functions[1].Name == `single` && file.Functions[1].Linkage == `C`)
+	assert(file.Functions[2].Name == `mangled` && file.Functions[2].Linkage == ``)
+	assert(file.Fields[0].Name == `counter` && file.Fields[0].IsExtern && file.Fields[0].Linkage == `C`)
+	assert(file.Fields[1].Name == `cpp` && file.Fields[1].Linkage == ``)
+	assert(file.Functions[3].Name == `before` && file.Functions[3].Linkage == `C`)
+	assert(file.Functions[4].Name == `overloaded` && file.Functions[4].Linkage == ``)
+	assert(file.Functions[5].Name == `after` && file.Functions[5].Linkage == `C`)
+
+	handle := file.Classes[0]
+	assert(handle.Fields[0].Linkage == `` && handle.Functions[0].Linkage == ``)
+	assert(file.Namespaces[0].Fields[0].Linkage == `C` && !file.Namespaces[0].Fields[0].IsExtern)
+}
+
+func TestParser_ParseFunctionQualifiers(t *testing.T) {
+	p := NewParser([]byte(`
+class Foo : public Bar {
+public:
+	[[nodiscard]] int size() const noexcept;
+	void swap(Foo &other) noexcept(false);
+	void move() noexcept(sizeof(int) == 4);
+	void old() throw();
+	void older() throw(std::bad_alloc, ...);
+	virtual void draw() const override final;
+	virtual void clear() volatile override = 0;
+	Foo &self() & { return *this; }
+	Foo &&self() && = delete;
+	auto get() const -> const int &;
+	[[nodiscard("check")]] static auto make() -> Foo;
+	friend bool operator==(const Foo &a, const Foo &b) noexcept { return true; }
+	auto product(int a, int b) -> decltype(a *  b);
+	decltype(auto) forward() const;
+	decltype(size()) length;
+	const decltype(std::declval<Foo>().size()) *cached;
+};
+`))
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	functions := file.Classes[0].Functions
+	assert(len(functions) == 13, len(functions))
+	assert(functions[0].IsNodiscard && functions[0].IsConst && functions[0].IsNoexcept)
+	assert(!functions[1].IsNoexcept && functions[1].NoexceptExpression == `false`)
+	assert(functions[2].IsNoexcept && functions[2].NoexceptExpression == `sizeof(int) == 4`)
+	assert(functions[3].HasThrowSpecification && functions[3].IsNoexcept)
+	assert(len(functions[4].ThrowTypes) == 2 && !functions[4].IsNoexcept)
+	assert(functions[4].ThrowTypes[0].LiteralName == `std::bad_alloc` && functions[4].ThrowTypes[1].LiteralName == `...`)
+	assert(functions[5].IsOverride && functions[5].IsFinal && functions[5].IsConst && functions[5].IsVirtual)
+	assert(functions[6].IsVolatile && functions[6].IsOverride && functions[6].IsPure)
+	assert(functions[7].RefQualifier == `&` && functions[8].RefQualifier == `&&` && functions[8].IsDeleted)
+	assert(functions[9].HasTrailingReturnType && functions[9].ReturnType.NodeType == kReference)
+	assert(functions[9].ReturnType.ReferenceBase.IsConst && functions[9].IsConst)
+	assert(functions[10].IsNodiscard && functions[10].IsStatic && functions[10].ReturnType.LiteralName == `Foo`)
+	friend := file.Classes[0].Friends[0].Function
+	assert(friend.IsFriend && friend.Operator == `==` && friend.IsNoexcept)
+	assert(functions[11].ReturnType.NodeType == kLiteral && functions[11].ReturnType.LiteralName == `decltype(a * b)`)
+	assert(len(functions[11].Arguments) == 2)
+	assert(functions[12].ReturnType.LiteralName == `decltype(auto)` && functions[12].IsConst)
+	fields := file.Classes[0].Fields
+	assert(len(fields) == 2 && fields[0].Name == `length` && fields[0].Type.LiteralName == `decltype(size())`)
+	cached := fields[1].Type
+	assert(cached.NodeType == kPointer && cached.PointerBase.IsConst, marshalJson(cached))
+	assert(cached.PointerBase.LiteralName == `decltype(std::declval<Foo>().size())`)
+}
+
+func TestParser_ParseFieldDeclarators(t *testing.T) {
+	p := NewParser([]byte(`
+#define SIZE 4
+struct Fields {
+	int data[16];
+	float matrix[SIZE][2 * 2];
+	unsigned flags : 3;
+	int : 0;
+	int count = 0;
+	std::string name{"x"};
+	int x, y, *z, &r = x;
+	const char *first = "a", second[] = "b";
+	std::map<int, int> lookup = {{1, 2}, {3, 4}};
+	int bits : 4 = 1; // C++20
+	static constexpr int kMax = SIZE * 2;
+};
+extern int table[];
+`))
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	fields := file.Classes[0].Fields
+	names := []string{}
+	for _, one := range fields {
+		names = append(names, one.Name)
+	}
+	assert(strings.Join(names, ` `) == `data matrix flags  count name x y z r first second lookup bits kMax`, names)
+
+	data := fields[0].Type
+	assert(data.NodeType == kArray && data.ArraySize == `16` && data.ArrayBase.LiteralName == `int`)
+	matrix := fields[1].Type
+	assert(matrix.ArraySize == `SIZE` && matrix.ArrayBase.NodeType == kArray && matrix.ArrayBase.ArraySize == `2 * 2`)
+	size, ok := p.Evaluate(matrix.ParsedArraySize)
+	assert(ok && size == 4)
+	assert(fields[2].BitWidth == `3` && fields[2].Type.LiteralName == `unsigned int`)
+	assert(fields[3].Name == `` && fields[3].BitWidth == `0`)
+	assert(fields[4].DefaultValue == `0` && fields[4].ParsedDefaultValue != nil)
+	assert(fields[5].DefaultValue == `{"x"}` && fields[5].ParsedDefaultValue == nil)
+	assert(fields[6].Type.LiteralName == `int` && fields[7].Type.LiteralName == `int`)
+	assert(fields[8].Type.NodeType == kPointer && fields[9].Type.NodeType == kReference && fields[9].DefaultValue == `x`)
+	assert(fields[10].Type.NodeType == kPointer && fields[10].Type.PointerBase.IsConst && fields[10].DefaultValue == `"a"`)
+	second := fields[11].Type
+	assert(second.NodeType == kArray && second.ArraySize == `` && second.ArrayBase.IsConst && second.ArrayBase.LiteralName == `char`)
+	assert(fields[12].DefaultValue == `{{1, 2}, {3, 4}}` && fields[12].Type.TemplateName == `std::map`)
+	assert(fields[13].BitWidth == `4` && fields[13].DefaultValue == `1` && fields[13].TrailingComment == `C++20`)
+	value, ok := p.Evaluate(fields[14].ParsedDefaultValue)
+	assert(fields[14].IsStatic && ok && value == 8)
+
+	table := file.Fields[0]
+	assert(table.IsExtern && table.Type.NodeType == kArray && table.Type.ArrayBase.LiteralName == `int`)
+}
+
+func TestParser_ParseBuiltinTypes(t *testing.T) {
+	for _, one := range []struct {
+		input   string
+		literal string
+		isConst bool
+	}{
+		{`int`, `int`, false},
+		{`unsigned`, `unsigned int`, false},
+		{`unsigned int`, `unsigned int`, false},
+		{`signed`, `int`, false},
+		{`short int`, `short`, false},
+		{`unsigned short int`, `unsigned short`, false},
+		{`long unsigned`, `unsigned long`, false},
+		{`long long int`, `long long`, false},
+		{`long unsigned int long`, `unsigned long long`, false},
+		{`long double`, `long double`, false},
+		{`double long`, `long double`, false},
+		{`signed char`, `signed char`, false},
+		{`char signed`, `signed char`, false},
+		{`unsigned const long`, `unsigned long`, true},
+		{`int const`, `int`, true},
+		{`const wchar_t`, `wchar_t`, true},
+		{`__int128`, `__int128`, false},
+		{`signed __int128`, `__int128`, false},
+		{`__int128 unsigned`, `unsigned __int128`, false},
+	} {
+		p := NewParser([]byte(one.input + ` name;`))
+		node, err := p.ParseTypeNode()
+		assert(err == nil, one.input, err)
+		assert(node.NodeType == kLiteral && node.LiteralName == one.literal, one.input, node.LiteralName)
+		assert(node.IsConst == one.isConst, one.input)
+	}
+	for _, input := range []string{`long long long`, `unsigned signed`, `short long`, `unsigned double`, `int int`, `void int`, `float int`, `long __int128`} {
+		_, err := NewParser([]byte(input + ` name;`)).ParseTypeNode()
+		assert(err != nil, input)
+	}
+
+	p := NewParser([]byte(`
+const unsigned char* data;
+unsigned int count : 4;
+short int f(signed, long double value);
+struct S { unsigned __int128 d; int e; };
+`))
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	fields := file.Classes[0].Fields
+	assert(len(fields) == 2 && fields[0].Name == `d` && fields[0].Type.LiteralName == `unsigned __int128`, marshalJson(fields))
+	data := file.Fields[0]
+	assert(data.Name == `data` && data.Type.NodeType == kPointer)
+	assert(data.Type.PointerBase.LiteralName == `unsigned char` && data.Type.PointerBase.IsConst)
+	assert(file.Fields[1].Name == `count` && file.Fields[1].Type.LiteralName == `unsigned int`)
+	f := file.Functions[0]
+	assert(f.Name == `f` && f.ReturnType.LiteralName == `short`)
+	assert(len(f.Arguments) == 2 && f.Arguments[0].Type.LiteralName == `int` && f.Arguments[0].Name == ``)
+	assert(f.Arguments[1].Type.LiteralName == `long double` && f.Arguments[1].Name == `value`)
+}
+
+// describeType spells out the type, e.g. pointer to function(int) returning void
+func describeType(node *TypeNode) string {
+	prefix := ``
+	if node.IsConst {
+		prefix += `const `
+	}
+	if node.IsVolatile {
+		prefix += `volatile `
+	}
+	switch node.NodeType {
+	case kLiteral:
+		return prefix + node.LiteralName
+	case kTemplate:
+		var arguments []string
+		for _, one := range node.TemplateArguments {
+			arguments = append(arguments, describeType(one))
+		}
+		return prefix + node.TemplateName + `<` + strings.Join(arguments, `, `) + `>`
+	case kValue:
+		return node.Value
+	case kPointer:
+		return prefix + `pointer to ` + describeType(node.PointerBase)
+	case kReference:
+		return prefix + `reference to ` + describeType(node.ReferenceBase)
+	case kLReference:
+		return prefix + `rvalue reference to ` + describeType(node.LReferenceBase)
+	case kMemberPointer:
+		return prefix + `pointer to member of ` + node.MemberPointerClass + ` of type ` + describeType(node.MemberPointerBase)
+	case kArray:
+		return prefix + `array[` + node.ArraySize + `] of ` + describeType(node.ArrayBase)
+	case kFunction:
+		var arguments []string
+		for _, one := range node.FunctionArguments {
+			arguments = append(arguments, strings.TrimSpace(describeType(one.Type)+` `+one.Name))
+		}
+		if node.FunctionIsNoexcept {
+			prefix += `noexcept `
+		}
+		return prefix + `function(` + strings.Join(arguments, `, `) + `) returning ` + describeType(node.FunctionReturns)
+	}
+	return `?`
+}
+
+func TestParser_ParseDeclarator(t *testing.T) {
+	for _, one := range []struct {
+		input string
+		name  string
+		type_ string
+	}{
+		{`int x`, `x`, `int`},
+		{`const char *name`, `name`, `pointer to const char`},
+		{`char * const p`, `p`, `const pointer to char`},
+		{`int * volatile * p`, `p`, `pointer to volatile pointer to int`},
+		{`int &r`, `r`, `reference to int`},
+		{`std::string &&s`, `s`, `rvalue reference to std::string`},
+		{`int values[4]`, `values`, `array[4] of int`},
+		{`int matrix[2][3]`, `matrix`, `array[2] of array[3] of int`},
+		{`int values[]`, `values`, `array[] of int`},
+		{`int *pointers[4]`, `pointers`, `array[4] of pointer to int`},
+		{`int (*rows)[4]`, `rows`, `pointer to array[4] of int`},
+		{`int (&row)[4]`, `row`, `reference to array[4] of int`},
+		{`void (*callback)(int)`, `callback`, `pointer to function(int) returning void`},
+		{`void (*callback)(int code, const char *message)`, `callback`, `pointer to function(int code, pointer to const char message) returning void`},
+		{`int (*print)(const char *, ...)`, `print`, `pointer to function(pointer to const char, ...) returning int`},
+		{`void (&handler)()`, `handler`, `reference to function() returning void`},
+		{`void (* const callback)()`, `callback`, `const pointer to function() returning void`},
+		{`void (*handlers[4])(int)`, `handlers`, `array[4] of pointer to function(int) returning void`},
+		{`void (*(*factory)(int))(double)`, `factory`, `pointer to function(int) returning pointer to function(double) returning void`},
+		{`int (*(*lookup)(const char *))[8]`, `lookup`, `pointer to function(pointer to const char) returning pointer to array[8] of int`},
+		{`void (*select(int))(double)`, `select`, `function(int) returning pointer to function(double) returning void`},
+		{`void apply(void (*)(int), int)`, `apply`, `function(pointer to function(int) returning void, int) returning void`},
+		{`int ((*nested))(int)`, `nested`, `pointer to function(int) returning int`},
+		{`void (*callback)() noexcept`, `callback`, `pointer to noexcept function() returning void`},
+		{`int Foo::*member`, `member`, `pointer to member of Foo of type int`},
+		{`int ns::Foo::*member`, `member`, `pointer to member of ns::Foo of type int`},
+		{`void (Foo::*method)(int)`, `method`, `pointer to member of Foo of type function(int) returning void`},
+		{`int (Foo::*getter)() const`, `getter`, `pointer to member of Foo of type const function() returning int`},
+		{`std::function<void(int)> f`, `f`, `std::function<function(int) returning void>`},
+		{`int`, ``, `int`},
+		{`int *`, ``, `pointer to int`},
+		{`int[4]`, ``, `array[4] of int`},
+		{`int (*)[4]`, ``, `pointer to array[4] of int`},
+		{`void (*)(int)`, ``, `pointer to function(int) returning void`},
+		{`void (*[2])(int)`, ``, `array[2] of pointer to function(int) returning void`},
+		{`void (*(*)(int))(double)`, ``, `pointer to function(int) returning pointer to function(double) returning void`},
+		{`int Foo::*`, ``, `pointer to member of Foo of type int`},
+		{`void (Foo::*)(int)`, ``, `pointer to member of Foo of type function(int) returning void`},
+		{`void(int)`, ``, `function(int) returning void`},
+	} {
+		p := NewParser([]byte(one.input + `;`))
+		node, name, err := p.ParseDeclarator()
+		assert(err == nil, one.input, err)
+		assert(name == one.name, one.input, name)
+		assert(describeType(node) == one.type_, one.input, describeType(node))
+		assert(p.MatchSymbol(`;`), one.input)
+	}
+	for _, input := range []string{`void (*)(int`, `int (*x[4]`, `int (*p)[4`} {
+		_, _, err := NewParser([]byte(input)).ParseDeclarator()
+		assert(err != nil, input)
+	}
+
+	p := NewParser([]byte(`
+typedef void (*Callback)(int), Handler(int), *Ptr;
+typedef int Row[4], (*RowPtr)[4];
+void (*handlers[2])(int), (*fallback)(int);
+int Foo::*member;
+int (*select(int which))(int);
+struct Table { int (*rows)[4]; void (Table::*method)() const; } table, *tables[2];
+void apply(void (*callback)(int), int values[], int (&row)[4]);
+`))
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	aliases := file.TypeAliases
+	assert(len(aliases) == 5, len(aliases))
+	assert(aliases[0].Name == `Callback` && describeType(aliases[0].Type) == `pointer to function(int) returning void`)
+	assert(aliases[1].Name == `Handler` && describeType(aliases[1].Type) == `function(int) returning void`)
+	assert(aliases[2].Name == `Ptr` && describeType(aliases[2].Type) == `pointer to void`)
+	assert(aliases[3].Name == `Row` && describeType(aliases[3].Type) == `array[4] of int`)
+	assert(aliases[4].Name == `RowPtr` && describeType(aliases[4].Type) == `pointer to array[4] of int`)
+
+	fields := file.Fields
+	assert(len(fields) == 5, len(fields))
+	assert(fields[0].Name == `handlers` && describeType(fields[0].Type) == `array[2] of pointer to function(int) returning void`)
+	assert(fields[1].Name == `fallback` && describeType(fields[1].Type) == `pointer to function(int) returning void`)
+	assert(fields[2].Name == `member` && describeType(fields[2].Type) == `pointer to member of Foo of type int`)
+	assert(fields[3].Name == `table` && fields[4].Name == `tables`)
+	assert(describeType(fields[4].Type) == `array[2] of pointer to Table`)
+
+	table := file.Classes[0]
+	assert(len(table.Fields) == 2 && describeType(table.Fields[0].Type) == `pointer to array[4] of int`)
+	assert(describeType(table.Fields[1].Type) == `pointer to member of Table of type const function() returning void`)
+
+	functions := file.Functions
+	assert(len(functions) == 2, len(functions))
+	selectFunction := functions[0]
+	assert(selectFunction.Name == `select` && describeType(selectFunction.ReturnType) == `pointer to function(int) returning int`)
+	assert(len(selectFunction.Arguments) == 1 && selectFunction.Arguments[0].Name == `which`)
+	arguments := functions[1].Arguments
+	assert(len(arguments) == 3 && arguments[0].Name == `callback`)
+	assert(describeType(arguments[0].Type) == `pointer to function(int) returning void`)
+	assert(arguments[1].Name == `values` && describeType(arguments[1].Type) == `array[] of int`)
+	assert(arguments[2].Name == `row` && describeType(arguments[2].Type) == `reference to array[4] of int`)
+}
+
+func TestParser_ParseClassHeads(t *testing.T) {
+	p := NewParserWithOptions([]byte(`
+class MYLIB_API Widget final : public Base<T>, protected ns::Mixin, virtual public Shared, private virtual Counted {};
+struct OTHER_API [[deprecated]] Point : ::Origin, std::vector<std::pair<int, int>> {};
+template <class... Bases> class Combined : public Bases... {};
+class MYLIB_API Forward;
+class final {};
+struct Leaf final {};
+`), ParserOptions{ExportMacros: []string{`MYLIB_API`, `OTHER_API`}})
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	classes := file.Classes
+	assert(len(classes) == 5, len(classes))
+
+	widget := classes[0]
+	assert(widget.Name == `Widget` && widget.IsFinal && widget.ExportMacro == `MYLIB_API`)
+	bases := widget.Bases
+	assert(len(bases) == 4, len(bases))
+	assert(bases[0].Access == kPublic && !bases[0].IsVirtual && bases[0].Name == `Base<T>`)
+	assert(bases[0].Type.NodeType == kTemplate && bases[0].Type.TemplateName == `Base` && bases[0].Type.TemplateArguments[0].LiteralName == `T`)
+	assert(bases[1].Access == kProtected && bases[1].Type.LiteralName == `ns::Mixin`)
+	assert(bases[2].Access == kPublic && bases[2].IsVirtual && bases[2].Name == `Shared`)
+	assert(bases[3].Access == kPrivate && bases[3].IsVirtual && bases[3].Name == `Counted`)
+
+	point := classes[1]
+	assert(point.Name == `Point` && point.ExportMacro == `OTHER_API` && point.Attribute(`deprecated`) != nil)
+	assert(point.Bases[0].Access == kPublic && point.Bases[0].Type.LiteralName == `::Origin`)
+	assert(point.Bases[1].Name == `std::vector<std::pair<int, int>>` && point.Bases[1].Type.NodeType == kTemplate)
+
+	combined := classes[2]
+	assert(len(combined.Bases) == 1 && combined.Bases[0].IsPack && combined.Bases[0].Name == `Bases`)
+	assert(classes[3].Name == `final` && !classes[3].IsFinal)
+	assert(classes[4].Name == `Leaf` && classes[4].IsFinal && classes[4].ExportMacro == ``)
+}
+
+func TestParser_ParseUnknownExportMacros(t *testing.T) {
+	p := NewParser([]byte(`
+class MYLIB_API Foo { int x; };
+struct MYLIB_API Bar final : public Foo {};
+class MYLIB_API Outer<T>::Inner<int> {};
+class Alone final {};
+class MYLIB_API Baz;
+struct MYLIB_API Qux final;
+struct stat *buffer;
+struct Point origin = {0, 0};
+`))
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	classes := file.Classes
+	assert(len(classes) == 4, len(classes))
+	assert(classes[0].Name == `Foo` && classes[0].ExportMacro == `MYLIB_API` && len(classes[0].Fields) == 1)
+	assert(classes[0].Fields[0].Name == `x`)
+	assert(classes[1].Name == `Bar` && classes[1].ExportMacro == `MYLIB_API` && classes[1].IsFinal && len(classes[1].Bases) == 1)
+	assert(classes[2].QualifiedName() == `Outer::Inner` && classes[2].ExportMacro == `MYLIB_API`)
+	assert(classes[3].Name == `Alone` && classes[3].IsFinal && classes[3].ExportMacro == ``)
+	// Forward declarations behind an export macro are no variables
+	assert(len(file.Fields) == 2 && file.Fields[0].Name == `buffer` && file.Fields[1].Name == `origin`, marshalJson(file.Fields))
+}
+
+func TestParser_ParseFriendsAndStaticAsserts(t *testing.T) {
+	p := NewParser([]byte(`
+namespace ns {
+class Outer {
+	friend class Helper;
+	friend struct ns::Other;
+	friend Plain;
+	template <class T> friend class Box;
+	friend bool operator==(const Outer &, const Outer &);
+	friend void swap(Outer &a, Outer &b) { }
+	static_assert(sizeof(int) == 4, "int " "size");
+	static_assert(std::is_same<int, Int>::value, "Int is int");
+	static_assert(std::is_same<int, Int>::value);
+	class Inner { enum Kind { kA }; class Deeper; };
+	enum class Kind { kX };
+};
+class Outer::Inner::Deeper { int value; };
+static_assert(sizeof(Outer) > 0);
+}
+_Static_assert(1, "C11");
+`))
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	outer := file.Namespaces[0].Classes[0]
+	friends := outer.Friends
+	assert(len(friends) == 6, len(friends))
+	assert(friends[0].Name == `Helper` && friends[0].Type.LiteralName == `Helper` && friends[0].Function == nil)
+	assert(friends[1].Name == `ns::Other` && friends[1].Type.LiteralName == `ns::Other`)
+	assert(friends[2].Name == `Plain` && friends[3].Name == `Box` && friends[3].IsTemplate)
+	assert(friends[4].Name == `operator==` && friends[4].Function.IsFriend && friends[4].Type == nil)
+	assert(friends[5].Name == `swap` && friends[5].Function.Arguments[1].Name == `b` && friends[5].Line == 9)
+	// Friends are no members of the class
+	assert(friends[4].Function.QualifiedName() == `ns::operator==` && friends[5].Function.QualifiedName() == `ns::swap`)
+	assert(len(outer.Functions) == 0, len(outer.Functions))
+
+	asserts := outer.StaticAsserts
+	assert(len(asserts) == 3, len(asserts))
+	assert(asserts[0].Expression == `sizeof(int) == 4` && asserts[0].Message == `int size` && asserts[0].Scope == `ns::Outer`)
+	value, ok := p.Evaluate(asserts[0].ParsedExpression)
+	assert(ok && value == 1)
+	assert(asserts[1].Expression == `std::is_same<int, Int>::value` && asserts[1].Message == `Int is int`)
+	assert(asserts[2].Expression == `std::is_same<int, Int>::value` && asserts[2].Message == ``)
+	assert(file.Namespaces[0].StaticAsserts[0].Expression == `sizeof(Outer) > 0` && file.Namespaces[0].StaticAsserts[0].Line == 17)
+	assert(file.StaticAsserts[0].Message == `C11`)
+
+	inner := outer.Classes[0]
+	assert(inner.QualifiedName() == `ns::Outer::Inner` && inner.Enums[0].QualifiedName() == `ns::Outer::Inner::Kind`)
+	assert(outer.Enums[0].QualifiedName() == `ns::Outer::Kind`)
+	deeper := file.Namespaces[0].Classes[1]
+	assert(deeper.Name == `Deeper` && deeper.QualifiedName() == `ns::Outer::Inner::Deeper`)
+	assert(deeper.Fields[0].QualifiedName() == `ns::Outer::Inner::Deeper::value`)
+}
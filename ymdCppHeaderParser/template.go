@@ -0,0 +1,315 @@
+package ymdCppHeaderParser
+
+type TemplateParameterType string
+
+const (
+	kTypeParameter     TemplateParameterType = `kTypeParameter`     // typename T
+	kNonTypeParameter  TemplateParameterType = `kNonTypeParameter`  // int N
+	kTemplateParameter TemplateParameterType = `kTemplateParameter` // template<typename> class C
+)
+
+// Template is the template head of a class or a function
+type Template struct {
+	IsTemplate         bool                 `json:",omitempty"`
+	TemplateParameters []*TemplateParameter `json:",omitempty"` // Empty for an explicit specialization
+	// Arguments of an explicit or partial specialization, e.g. the int* of class Foo<int*>
+	SpecializationArguments []*TypeNode `json:",omitempty"`
+	// Constraint behind the template head, e.g. std::integral<T> of template <class T> requires std::integral<T>
+	RequiresClause string `json:",omitempty"`
+	// Heads of the enclosing class templates of a member defined outside of them, outermost first,
+	// e.g. the template <class T> of template <class T> template <class U> void A<T>::f(U)
+	OuterTemplates []*Template `json:",omitempty"`
+}
+
+// IsSpecialization tells whether this is an explicit (template<>) or a partial specialization
+func (this *Template) IsSpecialization() bool {
+	return this.IsTemplate && (len(this.TemplateParameters) == 0 || len(this.SpecializationArguments) != 0)
+}
+
+type TemplateParameter struct {
+	ParameterType TemplateParameterType `json:",omitempty"`
+	Name          string                `json:",omitempty"` // Empty for an unnamed parameter
+	IsPack        bool                  `json:",omitempty"` // typename... Ts
+	// Type constraint of a type parameter, e.g. std::integral of template <std::integral T>
+	Constraint *TypeNode `json:",omitempty"`
+
+	// NonTypeParameter
+	Type *TypeNode `json:",omitempty"`
+
+	// TemplateParameter
+	TemplateParameters []*TemplateParameter `json:",omitempty"`
+
+	// Default of a type or a template parameter
+	DefaultType *TypeNode `json:",omitempty"`
+	// Default of a non-type parameter
+	DefaultValue       string      `json:",omitempty"`
+	ParsedDefaultValue *Expression `json:",omitempty"`
+}
+
+// parseTemplate parses the template head and then the declaration it belongs to
+func (this *Parser) parseTemplate() bool {
+	const funcId = `b6t2rk8e `
+	var startToken Token
+	if !this.GetIdentifier(&startToken) || startToken.Mtoken != `template` {
+		this.panicf(funcId, `Missing "template" identifier`)
+	}
+	// The heads in front of this one
+	outerTemplate := this.pendingTemplate
+	this.pendingTemplate = nil
+	// The parameters are known up to the end of the declaration
+	defer func(count int) {
+		this.templateParameters = this.templateParameters[:count]
+	}(len(this.templateParameters))
+	templateHead := &Template{IsTemplate: true}
+	if this.MatchSymbol(`<>`) {
+		// Explicit specialization
+		templateHead.TemplateParameters = []*TemplateParameter{}
+	} else if this.MatchSymbol(`<`) {
+		templateHead.TemplateParameters = this.parseTemplateParameters()
+	} else {
+		// Explicit instantiation, e.g. template class Foo<int>;
+		var token Token
+		return this.skipDeclaration(&token)
+	}
+	if this.MatchIdentifier(`requires`) {
+		templateHead.RequiresClause = this.scanRequiresClause()
+	}
+	if outerTemplate != nil {
+		templateHead.OuterTemplates = append(outerTemplate.OuterTemplates, outerTemplate)
+		outerTemplate.OuterTemplates = nil
+	}
+	this.debugPrintf(funcId, "template %v", marshalJson(templateHead))
+
+	var token Token
+	if !this.GetToken(&token, false, false) {
+		this.panicf(funcId, `Unexpected end of file`)
+	}
+	// The innermost head of a member template defined outside of its class is the one of the declaration
+	this.pendingTemplate = templateHead
+	ok := this.parseDeclaration(&token)
+	// Only classes and functions take the template
+	this.pendingTemplate = nil
+	return ok
+}
+
+// takeTemplate returns the template head of the declaration being parsed
+func (this *Parser) takeTemplate() Template {
+	if this.pendingTemplate == nil {
+		return Template{}
+	}
+	templateHead := *this.pendingTemplate
+	this.pendingTemplate = nil
+	return templateHead
+}
+
+// parseTemplateParameters parses the parameters behind the < of a template head up to the closing >
+func (this *Parser) parseTemplateParameters() []*TemplateParameter {
+	const funcId = `n4jq7w2s `
+	defer this.enter(funcId)()
+	parameters := []*TemplateParameter{}
+	if this.MatchSymbol(`>`) {
+		return parameters
+	}
+	for {
+		parameter := this.parseTemplateParameter()
+		parameters = append(parameters, parameter)
+		this.templateParameters = append(this.templateParameters, parameter)
+		if !this.MatchSymbol(`,`) {
+			break
+		}
+	}
+	if !this.MatchSymbol(`>`) {
+		this.panicf(funcId, `Expected closing >`)
+	}
+	return parameters
+}
+
+func (this *Parser) parseTemplateParameter() *TemplateParameter {
+	const funcId = `h8r5mc1x `
+	var token Token
+	if !this.GetToken(&token, false, false) {
+		this.panicf(funcId, `Unexpected end of file`)
+	}
+	parameter := &TemplateParameter{}
+	switch token.Mtoken {
+	case `template`:
+		// template<typename> class C
+		parameter.ParameterType = kTemplateParameter
+		this.requireSymbol(`<`)
+		parameter.TemplateParameters = this.parseTemplateParameters()
+		if !this.MatchIdentifier(`class`) && !this.MatchIdentifier(`typename`) {
+			this.panicf(funcId, `Expected "class" or "typename"`)
+		}
+	case `typename`, `class`:
+		parameter.ParameterType = kTypeParameter
+		// typename T::type N is a non-type parameter
+		var nameToken Token
+		if this.GetIdentifier(&nameToken) {
+			isType := this.MatchSymbol(`::`)
+			this.UngetToken(&nameToken)
+			if isType {
+				this.UngetToken(&token)
+				parameter.ParameterType = kNonTypeParameter
+				parameter.Type = this.parseTypeNode()
+			}
+		}
+	default:
+		this.UngetToken(&token)
+		parameter.ParameterType = kNonTypeParameter
+		parameter.Type = this.parseTypeNode()
+		// A concept may be declared in another header, e.g. template <Hashable T>
+		if this.isConcept(parameter.Type) || (this.isUnknownTypeName(parameter.Type) && this.isTypeParameterName()) {
+			parameter.ParameterType = kTypeParameter
+			parameter.Constraint = parameter.Type
+			parameter.Type = nil
+		}
+	}
+
+	parameter.IsPack = this.MatchSymbol(`...`)
+	var nameToken Token
+	if this.GetIdentifier(&nameToken) {
+		parameter.Name = nameToken.Mtoken
+	}
+
+	if this.MatchSymbol(`=`) {
+		if parameter.ParameterType == kNonTypeParameter {
+			parameter.DefaultValue, _ = this.scanTemplateArgument()
+			parameter.ParsedDefaultValue, _ = ParseExpression(parameter.DefaultValue)
+		} else {
+			parameter.DefaultType = this.parseTemplateArgument()
+		}
+	}
+	return parameter
+}
+
+// parseTemplateArgumentList parses the arguments of a template-id including the angle brackets,
+// it returns nil if there are none
+func (this *Parser) parseTemplateArgumentList() []*TypeNode {
+	if this.MatchSymbol(`<>`) {
+		return []*TypeNode{}
+	}
+	if this.MatchSymbol(`<`) {
+		return this.parseTemplateArguments()
+	}
+	return nil
+}
+
+// parseTemplateArguments parses the arguments behind the < of a template-id up to the closing >
+func (this *Parser) parseTemplateArguments() []*TypeNode {
+	const funcId = `w0e3pz6v `
+	defer this.enter(funcId)()
+	arguments := []*TypeNode{}
+	if this.MatchSymbol(`>`) {
+		return arguments
+	}
+	for {
+		arguments = append(arguments, this.parseTemplateArgument())
+		if !this.MatchSymbol(`,`) {
+			break
+		}
+	}
+	if !this.MatchSymbol(`>`) {
+		this.panicf(funcId, `Expected closing >`)
+	}
+	return arguments
+}
+
+// isValueStart tells whether a template argument starting with the token is a constant rather than a type
+func isValueStart(token *Token) bool {
+	return token.MtokenType == kConst || (token.MtokenType == kSymbol && token.Mtoken != `::`) || expressionKeywords[token.Mtoken]
+}
+
+// parseTemplateArgument parses a type or a constant, e.g. the int or the 4 of std::array<int, 4>
+func (this *Parser) parseTemplateArgument() *TypeNode {
+	const funcId = `k3v9sd5a `
+	startToken := Token{MstartPos: this.cursorPos, MstartLine: this.cursorLine}
+	source, terminated := this.scanTemplateArgument()
+	if source == `` || !terminated {
+		this.panicf(funcId, `Expected template argument`)
+	}
+
+	// Try the argument as a type first, a type has to take the whole argument
+	endToken := Token{MstartPos: this.cursorPos, MstartLine: this.cursorLine}
+	this.UngetToken(&startToken)
+	var token Token
+	this.GetToken(&token, false, true)
+	this.UngetToken(&token)
+	if isValueStart(&token) {
+		this.UngetToken(&endToken)
+		return NewValueNode(source)
+	}
+	node, ok := this.tryParse(this.parseTypeNode)
+	if ok && this.cursorPos <= endToken.MstartPos {
+		if !this.GetToken(&token, false, true) || token.MstartPos >= endToken.MstartPos {
+			this.UngetToken(&endToken)
+			if node != nil {
+				return node
+			}
+		}
+	}
+	this.UngetToken(&endToken)
+	return NewValueNode(source)
+}
+
+// expressionKeywords start an expression but never a type
+var expressionKeywords = map[string]bool{
+	`sizeof`: true, `alignof`: true, `noexcept`: true, `nullptr`: true,
+	`static_cast`: true, `const_cast`: true, `reinterpret_cast`: true, `dynamic_cast`: true,
+}
+
+// scanTemplateArgument returns the source up to the next , or > outside of parentheses and nested
+// template argument lists. It tells whether such a token ends the argument before the end of the file.
+func (this *Parser) scanTemplateArgument() (source string, terminated bool) {
+	var token Token
+	startPos := -1
+	endPos := -1
+	depth := 0      // parentheses, brackets and braces
+	angleDepth := 0 // template argument lists, only outside of parentheses
+	for this.GetToken(&token, false, true) {
+		if token.MtokenType == kSymbol {
+			if depth == 0 && angleDepth == 0 && (token.Mtoken == `,` || token.Mtoken == `>` || token.Mtoken == `;` ||
+				token.Mtoken == `)` || token.Mtoken == `]` || token.Mtoken == `}`) {
+				this.UngetToken(&token)
+				terminated = true
+				break
+			}
+			switch token.Mtoken {
+			case `(`, `[`, `{`:
+				depth++
+			case `)`, `]`, `}`:
+				depth--
+			case `<`:
+				if depth == 0 {
+					angleDepth++
+				}
+			case `>`:
+				if depth == 0 {
+					angleDepth--
+				}
+			}
+		}
+		if startPos < 0 {
+			startPos = token.MstartPos
+		}
+		endPos = this.cursorPos
+	}
+	if startPos < 0 {
+		return ``, terminated
+	}
+	return string(this.input[startPos:endPos]), terminated
+}
+
+// tryParse runs parse and tells whether it succeeded, on error the error is dropped
+func (this *Parser) tryParse(parse func() *TypeNode) (node *TypeNode, ok bool) {
+	nesting := this.nesting
+	defer func() {
+		if r := recover(); r != nil {
+			if _, isParseError := r.(*ParseError); !isParseError {
+				panic(r)
+			}
+			this.nesting = nesting
+			ok = false
+		}
+	}()
+	return parse(), true
+}
@@ -0,0 +1,123 @@
+package ymdCppHeaderParser
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParser_ParseTemplate(t *testing.T) {
+	p := NewParser([]byte(`
+template <typename T, int N = 4 * 2, class... Ts, template <typename> class C = std::vector, typename T::size_type S = 0>
+class Array {
+public:
+	template <typename U>
+	U get() const;
+	int size;
+};
+
+template <>
+class Array<bool, 1> {};
+
+template <typename T>
+struct Array<T*, (4 > 2)> {};
+
+template <class T>
+T max(T a, T b);
+
+template <>
+int max<int>(int a, int b);
+
+template <class T>
+int variable;
+
+template class Array<int>;
+std::array<std::pair<int, float>, sizeof(int)> values;
+`))
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	assert(len(file.Classes) == 3)
+
+	array := file.Classes[0]
+	assert(array.IsTemplate && !array.IsSpecialization())
+	parameters := array.TemplateParameters
+	assert(len(parameters) == 5)
+	assert(parameters[0].ParameterType == kTypeParameter && parameters[0].Name == `T`)
+	assert(parameters[1].ParameterType == kNonTypeParameter && parameters[1].Type.LiteralName == `int`)
+	assert(parameters[1].Name == `N` && parameters[1].DefaultValue == `4 * 2`)
+	value, ok := parameters[1].ParsedDefaultValue.Evaluate(nil)
+	assert(ok && value == 8)
+	assert(parameters[2].ParameterType == kTypeParameter && parameters[2].IsPack && parameters[2].Name == `Ts`)
+	assert(parameters[3].ParameterType == kTemplateParameter && parameters[3].Name == `C`)
+	assert(len(parameters[3].TemplateParameters) == 1 && parameters[3].DefaultType.LiteralName == `std::vector`)
+	assert(parameters[4].ParameterType == kNonTypeParameter && parameters[4].Type.LiteralName == `T::size_type`)
+
+	get := array.Functions[0]
+	assert(get.Name == `get` && get.IsTemplate && get.TemplateParameters[0].Name == `U`)
+	assert(len(array.Fields) == 1 && get.IsConst)
+
+	explicit := file.Classes[1]
+	assert(explicit.IsSpecialization() && len(explicit.TemplateParameters) == 0)
+	assert(len(explicit.SpecializationArguments) == 2)
+	assert(explicit.SpecializationArguments[0].LiteralName == `bool`)
+	assert(explicit.SpecializationArguments[1].NodeType == kValue && explicit.SpecializationArguments[1].Value == `1`)
+
+	partial := file.Classes[2]
+	assert(partial.IsSpecialization() && len(partial.TemplateParameters) == 1)
+	assert(partial.SpecializationArguments[0].NodeType == kPointer)
+	assert(partial.SpecializationArguments[1].Value == `(4 > 2)`)
+
+	assert(len(file.Functions) == 2)
+	max := file.Functions[0]
+	assert(max.IsTemplate && !max.IsSpecialization() && len(max.Arguments) == 2)
+	maxInt := file.Functions[1]
+	assert(maxInt.IsSpecialization() && maxInt.SpecializationArguments[0].LiteralName == `int`)
+
+	deduced, err := NewParser([]byte(`template <> void f<>(std::less<> less);`)).ParseAll()
+	assert(err == nil, err)
+	assert(deduced.Functions[0].IsSpecialization() && len(deduced.Functions[0].SpecializationArguments) == 0)
+	assert(deduced.Functions[0].Arguments[0].Type.TemplateName == `std::less`)
+
+	// The variable template is a field without template information
+	assert(len(file.Fields) == 2 && file.Fields[0].Name == `variable`)
+	values := file.Fields[1].Type
+	assert(values.NodeType == kTemplate && len(values.TemplateArguments) == 2)
+	assert(values.TemplateArguments[0].TemplateName == `std::pair`)
+	assert(values.TemplateArguments[1].NodeType == kValue)
+	size, ok := values.TemplateArguments[1].ParsedValue.Evaluate(nil)
+	assert(ok && size == 4)
+}
+
+func TestParser_ParseNestedTemplateHeads(t *testing.T) {
+	p := NewParser([]byte(`
+template <class T> template <class U> void A<T>::f(U u) {}
+template <class T> template <int N> requires (N > 0) template <class V> struct A<T>::B<N>::C {};
+template <class T> void g(T);
+`))
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	f := file.Functions[0]
+	assert(f.Name == `f` && f.IsTemplate && len(f.TemplateParameters) == 1 && f.TemplateParameters[0].Name == `U`)
+	assert(len(f.OuterTemplates) == 1 && f.OuterTemplates[0].TemplateParameters[0].Name == `T`, marshalJson(f.Template))
+	assert(len(f.OuterTemplates[0].OuterTemplates) == 0 && f.QualifiedName() == `A::f`)
+
+	c := file.Classes[0]
+	assert(c.QualifiedName() == `A::B::C` && c.TemplateParameters[0].Name == `V`, marshalJson(c.Template))
+	assert(len(c.OuterTemplates) == 2 && c.OuterTemplates[0].TemplateParameters[0].Name == `T`)
+	assert(c.OuterTemplates[1].TemplateParameters[0].Name == `N` && c.OuterTemplates[1].RequiresClause == `(N > 0)`)
+
+	g := file.Functions[1]
+	assert(g.IsTemplate && len(g.OuterTemplates) == 0)
+}
+
+func TestParser_ParseTemplateErrors(t *testing.T) {
+	for _, input := range []string{
+		`template <typename T class A {};`,
+		`template <template <typename> T> class A {};`,
+		`std::array<int, > a;`,
+		`template <`,
+	} {
+		_, err := NewParser([]byte(input)).ParseAll()
+		var parseError *ParseError
+		assert(errors.As(err, &parseError) && parseError.Code != `internal`, input, err)
+	}
+}
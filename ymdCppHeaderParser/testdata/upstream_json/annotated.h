@@ -0,0 +1,35 @@
+// Input of TestMarshalUpstreamJson_Annotated with the options of -c TCLASS -e TENUM -f TFUNC -p TPROPERTY -a, annotated.json is written by this package (go test -update) and reviewed by hand
+
+#include <string>
+
+namespace game
+{
+	TENUM()
+	enum class Team : int
+	{
+		Red,
+		Blue = 2,
+	};
+
+	TCLASS(Serializable, Name = "Player")
+	class Player : public Actor
+	{
+	public:
+		TFUNC(Category = Gameplay)
+		void Attack(Player *target, float damage = 1.5f);
+
+		// Not annotated, not reported
+		void Tick(float delta);
+
+		TPROPERTY(Range(Min = 0, Max = 100), Tooltip = "Current health")
+		int Health;
+
+		int Ignored;
+	};
+
+	class NotReflected
+	{
+		TPROPERTY()
+		int Lost;
+	};
+}
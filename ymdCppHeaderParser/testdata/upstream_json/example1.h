@@ -0,0 +1,41 @@
+// Input of TestMarshalUpstreamJson, example1.json is written by this package (go test -update) and reviewed by hand
+
+#include <vector>
+#include "foo.h"
+
+namespace test
+{
+	class Foo : public Bar
+	{
+	protected:
+		bool ProtectedFunction(std::vector<int> args, const char *name = "x", int count = 3) const;
+
+	public:
+		enum Enum
+		{
+			FirstValue,
+			SecondValue = 3
+		};
+
+		virtual void inProgress() = 0;
+		static inline int Count(char c);
+
+	public:
+		int ThisIsAProperty;
+		mutable const Foo* Next;
+	};
+
+	struct Point
+	{
+		float x;
+		float y;
+	};
+
+	enum class Color : unsigned
+	{
+		Red = 1 << 0,
+		Green = 1 << 1,
+	};
+
+	int Global(Point &p, std::map<int, std::string> *names);
+}
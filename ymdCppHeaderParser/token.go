@@ -16,6 +16,7 @@ const (
 	kBoolean ConstType = `kBoolean`
 	kInt64   ConstType = `kInt64`
 	kFloat64 ConstType = `kFloat64`
+	kChar    ConstType = `kChar` // A character literal such as 'a' or '\n', its value is in Mint64Const
 )
 
 type Token struct {
@@ -37,4 +38,6 @@ type Comment struct {
 	text      string
 	startLine int
 	endLine   int
+	startPos  int
+	endPos    int // just behind the comment, a line comment ends in front of the line break
 }
@@ -2,18 +2,21 @@ package ymdCppHeaderParser
 
 import (
 	"bytes"
-	"strconv"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 type Tokenizer struct {
+	FileName string // Reported in the errors
+
 	input          []byte
 	cursorPos      int
 	cursorLine     int
 	prevCursorPos  int
 	prevCursorLine int
-	comment        Comment
-	lastComment    Comment
+	comments       []Comment // every comment lexed so far, in source order
 }
 
 const (
@@ -29,9 +32,12 @@ func NewTokenizer(input []byte, startingLine int) *Tokenizer {
 }
 
 func (this *Tokenizer) GetChar() byte {
-	c := this.input[this.cursorPos]
 	this.prevCursorPos = this.cursorPos
 	this.prevCursorLine = this.cursorLine
+	if this.is_eof() {
+		return EndOfFileChar
+	}
+	c := this.input[this.cursorPos]
 
 	//New line moves the cursor to the new line
 	if c == '\n' {
@@ -54,14 +60,6 @@ func (this *Tokenizer) peek() byte {
 }
 
 func (this *Tokenizer) GetLeadingChar() byte {
-	if this.comment.text == `` {
-		this.lastComment = this.comment
-	}
-
-	this.comment.text = ``
-	this.comment.startLine = this.cursorLine
-	this.comment.endLine = this.cursorLine
-
 	var c = EndOfFileChar
 	for !this.is_eof() {
 		c = this.GetChar()
@@ -72,127 +70,79 @@ func (this *Tokenizer) GetLeadingChar() byte {
 			}
 			break
 		}
-		if c == '\n' {
-			if this.comment.text != `` {
-				this.comment.text += "\n"
-			}
-			continue
-		}
 		if isSpace(c) || isControl(c) {
 			continue
 		}
 		// If this is a single line comment
 		next := this.peek()
 		if c == '/' && next == '/' {
-			lines := []string{}
-			indentationLastLine := 0
-
-			for !this.is_eof() && c == '/' && next == '/' {
+			comment := Comment{startPos: this.prevCursorPos, startLine: this.prevCursorLine}
+			// A comment behind code on the same line does not continue on the next lines
+			ownLine := this.startsLine(comment.startPos)
+			var lines []string
+			for {
 				// Search for the end of the line
-				line := ``
-				for c = this.GetChar(); c != EndOfFileChar && c != '\n'; c = this.GetChar() {
-					line = sAppend(line, c)
-				}
-				// Store the line
-				if lastSlashIndex, ok := find_first_not_of(line, "/"); !ok {
-					line = ""
-				} else {
-					line = line[:lastSlashIndex]
-				}
-
-				firstCharIndex, ok := find_first_not_of(line, " \t")
-				if ok {
-					line = ""
-				} else {
-					line = line[:firstCharIndex]
+				lineStart := this.cursorPos
+				for !this.is_eof() && this.peek() != '\n' {
+					this.GetChar()
 				}
+				comment.endPos = this.cursorPos
+				comment.endLine = this.cursorLine
+				lines = append(lines, commentLine(string(this.input[lineStart:this.cursorPos]), "/"))
 
-				if firstCharIndex > indentationLastLine && len(lines) != 0 {
-					lines[len(lines)-1 ] += " " + line
-				} else {
-					lines = append(lines, line)
-					indentationLastLine = firstCharIndex
+				// Only a comment on the very next line continues this one
+				if this.is_eof() {
+					break
 				}
-
-				// Check the next line
-				for c = this.GetChar(); !this.is_eof() && isSpace(c); c = this.GetChar() {
-					// nothing
+				pos := this.cursorPos + 1
+				for pos < len(this.input) && (this.input[pos] == ' ' || this.input[pos] == '\t') {
+					pos++
 				}
-
-				if !this.is_eof() {
-					next = this.peek()
+				if !ownLine || !bytes.HasPrefix(this.input[pos:], []byte("//")) {
+					break
 				}
-			}
-
-			if !this.is_eof() {
-				this.UngetChar()
-			}
-			// Build comment string
-			ss := bytes.NewBuffer(nil)
-			for i := 0; i < len(lines); i++ {
-				if i > 0 {
-					ss.WriteByte('\n')
+				for this.cursorPos < pos+1 {
+					this.GetChar()
 				}
-				ss.WriteString(lines[i])
 			}
-			this.comment.text = ss.String()
-			this.comment.endLine = this.cursorLine
+			comment.text = joinCommentLines(lines)
+			this.addComment(comment)
 			// Go to the next
 			continue
 		}
 		// If this is a block comment
 		if c == '/' && next == '*' {
-			// Search for the end of the block comment
-			lines := []string{}
-			line := ``
-
-			c = this.GetChar()
-			next = this.peek()
-			for c != EndOfFileChar && (c != '*' || next != '/') {
-				if c == '\n' {
-					if len(lines) != 0 || line == `` {
-						lines = append(lines, line)
-					}
-					line = ``
-				} else {
-					if line != `` || !isSpace(c) || c == '*' {
-						line = sAppend(line, c)
-					}
-				}
-				c = this.GetChar()
-				next = this.peek()
+			comment := Comment{startPos: this.prevCursorPos, startLine: this.prevCursorLine}
+			this.GetChar()
+			bodyStart := this.cursorPos
+			bodyEnd := len(this.input)
+			endPos := len(this.input)
+			if idx := bytes.Index(this.input[bodyStart:], []byte("*/")); idx >= 0 {
+				bodyEnd = bodyStart + idx
+				endPos = bodyEnd + 2
 			}
-
-			// Skip past the slash
-			if c != EndOfFileChar {
+			for this.cursorPos < endPos {
 				this.GetChar()
 			}
+			comment.endPos = this.cursorPos
+			comment.endLine = this.cursorLine
 
-			// Skip past new lines and spaces
-			c = this.GetChar()
-			for !this.is_eof() && isSpace(c) {
-				c = this.GetChar()
-			}
-			if !this.is_eof() {
-				this.UngetChar()
-			}
-
-			// Remove empty lines from the back
-			for len(lines) != 0 && lines[len(lines)-1] == `` {
-				lines = lines[len(lines)-1:]
-			}
-			// Build comment string
-			ss := bytes.NewBuffer(nil)
-			for i := 0; i < len(lines); i++ {
-				if i > 0 {
-					ss.WriteString("\n")
+			var lines []string
+			for idx, line := range strings.Split(string(this.input[bodyStart:bodyEnd]), "\n") {
+				if idx == 0 {
+					line = commentLine(line, "*")
+				} else {
+					// Strip the decoration of the continuation lines
+					line = strings.TrimLeft(line, " \t")
+					if strings.HasPrefix(line, "*") {
+						line = strings.TrimPrefix(line[1:], " ")
+					}
+					line = strings.TrimRight(line, " \t\r")
 				}
-				ss.WriteString(lines[i])
+				lines = append(lines, line)
 			}
-
-			this.comment.text = ss.String()
-			this.comment.endLine = this.cursorLine
-
+			comment.text = joinCommentLines(lines)
+			this.addComment(comment)
 			// Move to the next character
 			continue
 		}
@@ -201,6 +151,84 @@ func (this *Tokenizer) GetLeadingChar() byte {
 	return c
 }
 
+// commentLine strips the comment markers from the first line of a comment, this
+// includes the doxygen variants ///, //!, /**, /*! and the member markers ///< and /**<
+func commentLine(line string, markers string) string {
+	line = strings.TrimLeft(line, markers)
+	line = strings.TrimPrefix(line, "!")
+	line = strings.TrimPrefix(line, "<")
+	line = strings.TrimPrefix(line, " ")
+	return strings.TrimRight(line, " \t\r")
+}
+
+// joinCommentLines joins the lines without the empty lines at the front and at the back
+func joinCommentLines(lines []string) string {
+	for len(lines) != 0 && lines[0] == `` {
+		lines = lines[1:]
+	}
+	for len(lines) != 0 && lines[len(lines)-1] == `` {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// addComment records a comment, backtracking lexes the same comments again
+func (this *Tokenizer) addComment(comment Comment) {
+	if len(this.comments) != 0 && this.comments[len(this.comments)-1].startPos >= comment.startPos {
+		return
+	}
+	this.comments = append(this.comments, comment)
+}
+
+// LeadingComment returns the text of the comment in front of pos. The comment must start on its own
+// line and must be directly in front of pos, separated by white space and at most one line break.
+func (this *Tokenizer) LeadingComment(pos int) string {
+	idx := sort.Search(len(this.comments), func(i int) bool {
+		return this.comments[i].endPos > pos
+	}) - 1
+	if idx < 0 {
+		return ``
+	}
+	comment := this.comments[idx]
+	between := this.input[comment.endPos:pos]
+	if len(bytes.TrimLeft(between, " \t\r\n")) != 0 || bytes.Count(between, []byte("\n")) > 1 {
+		return ``
+	}
+	if !this.startsLine(comment.startPos) {
+		// This is the trailing comment of something else
+		return ``
+	}
+	return comment.text
+}
+
+// startsLine tells whether there is only white space in front of pos on its line
+func (this *Tokenizer) startsLine(pos int) bool {
+	lineStart := bytes.LastIndexByte(this.input[:pos], '\n') + 1
+	return len(bytes.TrimLeft(this.input[lineStart:pos], " \t")) == 0
+}
+
+// TrailingComment returns the text of the comment which follows pos on the same line.
+// Only white space, a comma or a semicolon may be in between.
+func (this *Tokenizer) TrailingComment(pos int) string {
+	// Make sure the comments behind the cursor are lexed
+	saved := *this
+	this.GetLeadingChar()
+	saved.comments = this.comments
+	*this = saved
+
+	idx := sort.Search(len(this.comments), func(i int) bool {
+		return this.comments[i].startPos >= pos
+	})
+	if idx == len(this.comments) {
+		return ``
+	}
+	comment := this.comments[idx]
+	if len(bytes.TrimLeft(this.input[pos:comment.startPos], " \t,;")) != 0 {
+		return ``
+	}
+	return comment.text
+}
+
 func (this *Tokenizer) GetToken(token *Token, angleBracketsForStrings bool, seperateBraces bool) bool {
 	// Get the next character
 	c := this.GetLeadingChar()
@@ -220,7 +248,6 @@ func (this *Tokenizer) GetToken(token *Token, angleBracketsForStrings bool, sepe
 	if isAlpha(c) || c == '_' {
 		// Read the rest of the alphanumeric characters
 		for {
-			token.Mtoken = sAppend(token.Mtoken, c)
 			c = this.GetChar()
 			if isAlnum(c) || c == '_' { // 字母数字或 _
 				continue
@@ -230,6 +257,14 @@ func (this *Tokenizer) GetToken(token *Token, angleBracketsForStrings bool, sepe
 				break
 			}
 		}
+		token.Mtoken = string(this.input[token.MstartPos:this.cursorPos])
+		// The encoding prefix of a character literal, e.g. L'a'
+		switch token.Mtoken {
+		case `L`, `u`, `U`, `u8`:
+			if this.peek() == '\'' && this.getCharLiteral(token) {
+				return true
+			}
+		}
 
 		// Set the type of the token
 		token.MtokenType = kIdentifier
@@ -245,51 +280,25 @@ func (this *Tokenizer) GetToken(token *Token, angleBracketsForStrings bool, sepe
 		}
 
 		return true
-	} else if isDigit(c) || ((c == '-' || c == '+') && isDigit(p)) { // Constant
-		isFloat := false
-		isHex := false
+	} else if isDigit(c) || ((c == '-' || c == '+' || c == '.') && isDigit(p)) { // Constant
+		// Read the whole number, e.g. 0x1F, 1'000u, -1.5e-3f
 		for {
-			if c == '.' {
-				isFloat = true
-			}
-
-			if c == 'x' || c == 'X' {
-				isHex = true
-			}
-			token.Mtoken = sAppend(token.Mtoken, c)
+			prev := c
 			c = this.GetChar()
 
-			if isDigit(c) {
-				continue
-			}
-			if (!isFloat && c == '.') {
+			if isAlnum(c) || c == '_' || c == '.' || c == '\'' {
 				continue
 			}
-			if (!isHex && (c == 'X' || c == 'x')) {
-				continue
-			}
-			if (isHex && isXDigit(c)) {
+			if (c == '-' || c == '+') && isExponent(prev, isHexNumber(string(this.input[token.MstartPos:this.prevCursorPos]))) {
 				continue
 			}
 			break
 		}
+		this.UngetChar()
+		token.Mtoken = string(this.input[token.MstartPos:this.cursorPos])
 
-		if !isFloat || (c != 'f' && c != 'F') {
-			this.UngetChar()
-		}
-
-		token.MtokenType = kConst
-		if !isFloat {
-			i, err := strconv.ParseInt(token.Mtoken, 0, 64)
-			assert(err == nil, token.Mtoken)
-			token.Mint64Const = i
-			token.MconstType = kInt64
-		} else {
-			f, err := strconv.ParseFloat(token.Mtoken, 64)
-			assert(err == nil, token.Mtoken)
-			token.Mfloat64Const = f
-			token.MconstType = kFloat64
-		}
+		// A number which can not be converted is returned as a kNone token
+		parseNumber(token)
 		return true
 	} else if c == '"' || (angleBracketsForStrings && c == '<') {
 		var closingElement byte = '"'
@@ -297,6 +306,7 @@ func (this *Tokenizer) GetToken(token *Token, angleBracketsForStrings bool, sepe
 			closingElement = '>'
 		}
 
+		var value []byte
 		c = this.GetChar()
 		for c != closingElement && c != EndOfFileChar {
 			if c == '\\' {
@@ -314,9 +324,10 @@ func (this *Tokenizer) GetToken(token *Token, angleBracketsForStrings bool, sepe
 				}
 			}
 
-			token.Mtoken = sAppend(token.Mtoken, c)
+			value = append(value, c)
 			c = this.GetChar()
 		}
+		token.Mtoken = string(value)
 		if c != closingElement {
 			this.UngetChar()
 		}
@@ -325,17 +336,25 @@ func (this *Tokenizer) GetToken(token *Token, angleBracketsForStrings bool, sepe
 		token.MconstType = kString
 		token.MstringConst = token.Mtoken
 
+		return true
+	} else if c == '\'' && this.getCharLiteral(token) {
 		return true
 	} else { // Symbol
 		// Push back the symbol
 		token.MtokenType = kSymbol
 		token.Mtoken = sAppend(token.Mtoken, c)
 		d := this.peek()
+		if c == '.' && d == '.' && this.cursorPos+1 < len(this.input) && this.input[this.cursorPos+1] == '.' {
+			token.Mtoken = `...`
+			this.GetChar()
+			this.GetChar()
+			return true
+		}
 		switch string([]byte{c, d}) {
 		case
 			`<>`, `->`, `!=`, `<=`, `>=`, `++`, `--`,
 			`+=`, `-=`, `*=`, `/=`, `^=`, `|=`, `&=`,
-			`~=`, `%=`, `||`, `==`, `::`:
+			`~=`, `%=`, `||`, `&&`, `<<`, `==`, `::`:
 			token.Mtoken = sAppend(token.Mtoken, d)
 			this.GetChar()
 		case `>>`:
@@ -346,7 +365,130 @@ func (this *Tokenizer) GetToken(token *Token, angleBracketsForStrings bool, sepe
 		}
 		return true
 	}
-	return false
+}
+
+// getCharLiteral reads the character literal starting at the ' in front of or behind the cursor into token.
+// A ' without a closing ' on the same line is left alone, the cursor is not moved then.
+func (this *Tokenizer) getCharLiteral(token *Token) bool {
+	start := this.cursorPos
+	if this.input[start-1] != '\'' {
+		start++
+	}
+	pos := start
+	var value int64
+	for pos < len(this.input) && this.input[pos] != '\'' && this.input[pos] != '\n' {
+		c := int64(this.input[pos])
+		pos++
+		if c == '\\' && pos < len(this.input) && this.input[pos] != '\n' {
+			var size int
+			c, size = unescapeChar(this.input[pos:])
+			pos += size
+		}
+		// Multicharacter literals such as 'ab' are implementation defined, this is what gcc does
+		value = value<<8 | c
+	}
+	if pos >= len(this.input) || this.input[pos] != '\'' || pos == start {
+		return false
+	}
+	for this.cursorPos <= pos {
+		this.GetChar()
+	}
+	token.Mtoken = string(this.input[token.MstartPos:this.cursorPos])
+	token.MtokenType = kConst
+	token.MconstType = kChar
+	token.Mint64Const = value
+	return true
+}
+
+// unescapeChar returns the character of the escape sequence behind a \ and the number of bytes it takes
+func unescapeChar(input []byte) (int64, int) {
+	switch input[0] {
+	case 'n':
+		return '\n', 1
+	case 't':
+		return '\t', 1
+	case 'r':
+		return '\r', 1
+	case 'a':
+		return '\a', 1
+	case 'b':
+		return '\b', 1
+	case 'f':
+		return '\f', 1
+	case 'v':
+		return '\v', 1
+	case 'x':
+		size := 1
+		for size < len(input) && isXDigit(input[size]) {
+			size++
+		}
+		value, _ := strconv.ParseUint(string(input[1:size]), 16, 64)
+		return int64(value), size
+	}
+	size := 0
+	for size < len(input) && size < 3 && '0' <= input[size] && input[size] <= '7' {
+		size++
+	}
+	if size != 0 {
+		value, _ := strconv.ParseUint(string(input[:size]), 8, 64)
+		return int64(value), size
+	}
+	// \' \" \\ \? stand for themselves
+	return int64(input[0]), 1
+}
+
+func isHexNumber(number string) bool {
+	number = strings.TrimLeft(number, `+-`)
+	return strings.HasPrefix(number, `0x`) || strings.HasPrefix(number, `0X`)
+}
+
+// isExponent tells whether c starts the exponent of a number, which may be followed by a sign
+func isExponent(c byte, isHex bool) bool {
+	if isHex {
+		return c == 'p' || c == 'P'
+	}
+	return c == 'e' || c == 'E'
+}
+
+// parseNumber converts the number in token.Mtoken into a kInt64 or kFloat64 constant,
+// a number which can not be converted leaves the token as kNone
+func parseNumber(token *Token) {
+	text := strings.Replace(token.Mtoken, `'`, ``, -1)
+	isHex := isHexNumber(text)
+	isFloat := strings.Contains(text, `.`) ||
+		(isHex && strings.ContainsAny(text, `pP`)) ||
+		(!isHex && strings.ContainsAny(text, `eE`))
+
+	if isFloat {
+		f, err := strconv.ParseFloat(strings.TrimRight(text, `fFlL`), 64)
+		if err != nil {
+			return
+		}
+		token.MtokenType = kConst
+		token.MconstType = kFloat64
+		token.Mfloat64Const = f
+		return
+	}
+
+	text = strings.TrimRight(text, `uUlLzZ`)
+	i, err := strconv.ParseInt(text, 0, 64)
+	if err != nil {
+		// Unsigned constants above the int64 range keep their bits
+		u, err := strconv.ParseUint(text, 0, 64)
+		if err != nil {
+			return
+		}
+		i = int64(u)
+	}
+	token.MtokenType = kConst
+	token.MconstType = kInt64
+	token.Mint64Const = i
+}
+
+// columnOf returns the column of the byte at pos, starting at 1
+func (this *Tokenizer) columnOf(pos int) int {
+	lineStart := bytes.LastIndexByte(this.input[:pos], '\n') + 1
+	return pos - lineStart + 1
 }
 
 func (this *Tokenizer) is_eof() bool {
@@ -395,18 +537,53 @@ func (this *Tokenizer) MatchSymbol(symbol string) bool {
 	return false
 }
 
-func (this *Tokenizer) RequireSymbol(symbol string) {
+func (this *Tokenizer) RequireSymbol(symbol string) (err error) {
+	defer this.catchParseError(&err)
+	this.requireSymbol(symbol)
+	return nil
+}
+
+func (this *Tokenizer) requireSymbol(symbol string) {
 	if !this.MatchSymbol(symbol) {
-		this.panicf(`8jn0qzkn`, `Missing symbol %v`, symbol)
+		this.panicf(`8jn0qzkn `, `Missing symbol %v`, symbol)
 	}
 }
 
-func (this *Tokenizer) panicf(funcId string, msg string, a ... interface{}) {
-	pre := this.cursorPos
-	if this.cursorPos > 5 {
-		pre = 5
+func (this *Tokenizer) panicf(funcId string, msg string, a ...interface{}) {
+	panic(this.newParseError(funcId, fmt.Sprintf(msg, a...)))
+}
+
+// newParseError reports an error at the start of the next token
+func (this *Tokenizer) newParseError(funcId string, msg string) *ParseError {
+	if this.cursorPos > len(this.input) {
+		this.cursorPos = len(this.input)
+	}
+	saved := *this
+	pos, line := this.cursorPos, this.cursorLine
+	if this.GetLeadingChar() != EndOfFileChar {
+		pos, line = this.prevCursorPos, this.prevCursorLine
+	}
+	*this = saved
+
+	return &ParseError{
+		File:    this.FileName,
+		Line:    line,
+		Column:  this.columnOf(pos),
+		Code:    strings.TrimSpace(funcId),
+		Message: msg,
+	}
+}
+
+// catchParseError recovers from the panic raised by panicf and stores the ParseError in err.
+// Any other panic is a bug of the parser, it is reported the same way so that no input can crash the caller.
+func (this *Tokenizer) catchParseError(err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if parseError, ok := r.(*ParseError); ok {
+		*err = parseError
+		return
 	}
-	as := a
-	as = append(as, string(this.input[this.cursorPos-pre:this.cursorPos]))
-	panic(fmt.Errorf(funcId+msg+" after \"%v\"\n", as...))
+	*err = this.newParseError(`internal `, fmt.Sprint(r))
 }
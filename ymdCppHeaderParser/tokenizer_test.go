@@ -78,4 +78,43 @@ func TestTokenizer_GetToken2(t *testing.T) {
 	var token Token
 	ok := tn.GetToken(&token, false, false)
 	assert(!ok)
-}
\ No newline at end of file
+}
+func TestTokenizer_GetTokenAtEnd(t *testing.T) {
+	tn := NewTokenizer([]byte(`a 10`), 1)
+	var token Token
+	assert(tn.GetToken(&token, false, false) && token.Mtoken == `a`)
+	assert(tn.GetToken(&token, false, false) && token.Mint64Const == 10)
+	assert(!tn.GetToken(&token, false, false))
+}
+
+func TestTokenizer_LeadingComment(t *testing.T) {
+	input := "/*! First\n *  indented\n */\nint /* inline */ a; // trailing\n\n// detached\n\nint b;"
+	tn := NewTokenizer([]byte(input), 1)
+	var token Token
+	assert(tn.GetToken(&token, false, false) && token.Mtoken == `int`)
+	assert(tn.LeadingComment(token.MstartPos) == "First\n indented", tn.LeadingComment(token.MstartPos))
+	assert(tn.GetToken(&token, false, false) && token.Mtoken == `a`)
+	assert(tn.LeadingComment(token.MstartPos) == ``)
+	assert(tn.GetToken(&token, false, false) && token.Mtoken == `;`)
+	assert(tn.TrailingComment(tn.cursorPos) == `trailing`)
+	assert(tn.GetToken(&token, false, false) && token.Mtoken == `int`)
+	assert(tn.LeadingComment(token.MstartPos) == ``)
+}
+
+func TestTokenizer_GetCharLiteral(t *testing.T) {
+	tn := NewTokenizer([]byte("'a' L'\\n' u8'\\x7F' 'ab' '\\'' ''\nL 'x"), 1)
+	var token Token
+	for _, want := range []struct {
+		source string
+		value  int64
+	}{{`'a'`, 'a'}, {`L'\n'`, '\n'}, {`u8'\x7F'`, 0x7F}, {`'ab'`, 'a'<<8 | 'b'}, {`'\''`, '\''}} {
+		assert(tn.GetToken(&token, false, false), want.source)
+		assert(token.Mtoken == want.source && token.MconstType == kChar && token.Mint64Const == want.value, token.Mtoken, token.Mint64Const)
+	}
+	// An empty or unterminated literal is lexed as before
+	assert(tn.GetToken(&token, false, false) && token.MtokenType == kSymbol && token.Mtoken == `'`)
+	assert(tn.GetToken(&token, false, false) && token.MtokenType == kSymbol && token.Mtoken == `'`)
+	assert(tn.GetToken(&token, false, false) && token.MtokenType == kIdentifier && token.Mtoken == `L`)
+	assert(tn.GetToken(&token, false, false) && token.MtokenType == kSymbol && token.Mtoken == `'`)
+	assert(tn.GetToken(&token, false, false) && token.Mtoken == `x`)
+}
@@ -0,0 +1,149 @@
+package ymdCppHeaderParser
+
+import "strings"
+
+// TypeAlias is a typedef or an alias declaration, e.g. using Ptr = std::shared_ptr<Foo>;
+type TypeAlias struct {
+	Declaration
+	Template           // Alias template, e.g. template <class T> using Vec = std::vector<T>;
+	Type     *TypeNode `json:",omitempty"` // The aliased type
+	IsUsing  bool      `json:",omitempty"` // using Name = Type; instead of typedef
+}
+
+// UsingDirective makes the names of a namespace visible in the scope, e.g. using namespace std;
+type UsingDirective struct {
+	Namespace string `json:",omitempty"` // As written, e.g. std::chrono
+	Scope     string `json:",omitempty"`
+	Line      int    `json:",omitempty"`
+}
+
+// UsingDeclaration brings a single name of another scope into the scope, e.g. using Base::method;
+// The name of the declaration is the last component of the target.
+type UsingDeclaration struct {
+	Declaration
+	Target     string `json:",omitempty"` // As written, e.g. Base::method or ::size_t
+	IsTypename bool   `json:",omitempty"` // using typename Base::type;
+}
+
+// parseTypedef parses typedef Type Name; with any declarator, e.g. typedef void (*Callback)(int);
+func (this *Parser) parseTypedef() bool {
+	const funcId = `q7m2xv4d `
+	var startToken Token
+	if !this.GetIdentifier(&startToken) || startToken.Mtoken != `typedef` {
+		this.panicf(funcId, `Missing "typedef" identifier`)
+	}
+	// typedef struct { ... } Point;
+	var next Token
+	if this.GetIdentifier(&next) {
+		this.UngetToken(&next)
+		if (next.Mtoken == `class` || next.Mtoken == `struct` || next.Mtoken == `union`) && this.isClassHead(true) {
+			this.UngetToken(&startToken)
+			return this.parseClass()
+		}
+	}
+	typeNode := this.parseTypeNode()
+
+	// typedef int Int, *IntPtr;
+	base := declaratorBase(typeNode)
+	var aliases []*TypeAlias
+	for {
+		node, nameToken := this.parseDeclarator(typeNode, false, true)
+		if nameToken.Mtoken == `` {
+			this.panicf(funcId, `Expected typedef name`)
+		}
+		alias := &TypeAlias{
+			Declaration: this.newDeclaration(nameToken.Mtoken, &startToken),
+			Type:        node,
+		}
+		aliases = append(aliases, alias)
+		this.addTypeAlias(alias)
+		if !this.MatchSymbol(`,`) {
+			break
+		}
+		typeNode = base
+	}
+	this.requireSymbol(`;`)
+	for _, one := range aliases {
+		this.endDeclaration(&one.Declaration)
+	}
+	return true
+}
+
+// parseUsing parses a using-directive, an alias declaration or a using-declaration
+func (this *Parser) parseUsing() bool {
+	const funcId = `j3w8fe5p `
+	var startToken Token
+	if !this.GetIdentifier(&startToken) || startToken.Mtoken != `using` {
+		this.panicf(funcId, `Missing "using" identifier`)
+	}
+	templateHead := this.takeTemplate()
+
+	// using namespace std;
+	if this.MatchIdentifier(`namespace`) {
+		directive := &UsingDirective{
+			Namespace: this.parseTypeNodeDeclarator(),
+			Scope:     this.scopeName(),
+			Line:      startToken.MstartLine,
+		}
+		this.requireSymbol(`;`)
+		members := this.topScope().members
+		members.UsingDirectives = append(members.UsingDirectives, directive)
+		return true
+	}
+
+	// using Ptr = std::shared_ptr<Foo>;
+	var nameToken Token
+	if this.GetIdentifier(&nameToken) {
+		this.parseAttributes()
+		if this.MatchSymbol(`=`) {
+			alias := &TypeAlias{
+				Declaration: this.newDeclaration(nameToken.Mtoken, &startToken),
+				Template:    templateHead,
+				Type:        this.parseTypeNode(),
+				IsUsing:     true,
+			}
+			this.requireSymbol(`;`)
+			this.endDeclaration(&alias.Declaration)
+			this.addTypeAlias(alias)
+			return true
+		}
+		this.UngetToken(&nameToken)
+	}
+
+	// using Base::method; using A::a, B::b;
+	for {
+		isTypename := this.MatchIdentifier(`typename`)
+		target := this.parseTypeNodeDeclarator()
+		name := target
+		if idx := strings.LastIndex(target, `::`); idx >= 0 {
+			name = target[idx+2:]
+		}
+		if name == `operator` {
+			name = operatorName(this.parseOperatorSymbol())
+			target = target[:len(target)-len(`operator`)] + name
+		}
+		this.MatchSymbol(`...`)
+		using := &UsingDeclaration{
+			Declaration: this.newDeclaration(name, &startToken),
+			Target:      target,
+			IsTypename:  isTypename,
+		}
+		if this.isReported(&using.Declaration) {
+			members := this.topScope().members
+			members.UsingDeclarations = append(members.UsingDeclarations, using)
+		}
+		if !this.MatchSymbol(`,`) {
+			break
+		}
+	}
+	this.requireSymbol(`;`)
+	return true
+}
+
+func (this *Parser) addTypeAlias(alias *TypeAlias) {
+	this.declareType(alias.Name)
+	if this.isReported(&alias.Declaration) {
+		members := this.topScope().members
+		members.TypeAliases = append(members.TypeAliases, alias)
+	}
+}
@@ -0,0 +1,69 @@
+package ymdCppHeaderParser
+
+import "testing"
+
+func TestParser_ParseTypeAlias(t *testing.T) {
+	p := NewParser([]byte(`
+using namespace std;
+typedef std::map<int, X> XMap;
+typedef void (*Callback)(int code, const char*);
+typedef int Handler(int);
+typedef struct Foo Foo;
+
+namespace ns {
+	using namespace std::chrono;
+	using Ptr = std::shared_ptr<Foo>;
+	template <class T>
+	using Vec = std::vector<T>;
+	using ::size_t;
+}
+
+class Derived : public Base {
+public:
+	using Base::Base;
+	using Base::method, Base::operator=;
+	using Base::operator new;
+protected:
+	using typename Base::value_type;
+	using Callback = Base::callback_type;
+};
+`))
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+
+	assert(len(file.UsingDirectives) == 1 && file.UsingDirectives[0].Namespace == `std`)
+	assert(len(file.TypeAliases) == 4)
+	xMap := file.TypeAliases[0]
+	assert(xMap.Name == `XMap` && !xMap.IsUsing && xMap.Line == 3)
+	assert(xMap.Type.NodeType == kTemplate && xMap.Type.TemplateName == `std::map` && len(xMap.Type.TemplateArguments) == 2)
+
+	callback := file.TypeAliases[1]
+	assert(callback.Name == `Callback` && callback.Type.NodeType == kPointer)
+	function := callback.Type.PointerBase
+	assert(function.NodeType == kFunction && function.FunctionReturns.LiteralName == `void`)
+	assert(len(function.FunctionArguments) == 2 && function.FunctionArguments[0].Name == `code`)
+	assert(file.TypeAliases[2].Name == `Handler` && file.TypeAliases[2].Type.NodeType == kFunction)
+	assert(file.TypeAliases[3].Name == `Foo` && file.TypeAliases[3].Type.LiteralName == `Foo`)
+	assert(len(file.Fields) == 0 && len(file.Functions) == 0)
+
+	ns := file.Namespaces[0]
+	assert(len(ns.UsingDirectives) == 1 && ns.UsingDirectives[0].Namespace == `std::chrono`)
+	assert(ns.UsingDirectives[0].Scope == `ns`)
+	assert(len(ns.TypeAliases) == 2)
+	assert(ns.TypeAliases[0].IsUsing && ns.TypeAliases[0].QualifiedName() == `ns::Ptr`)
+	assert(ns.TypeAliases[0].Type.TemplateName == `std::shared_ptr`)
+	vec := ns.TypeAliases[1]
+	assert(vec.Name == `Vec` && vec.IsTemplate && vec.TemplateParameters[0].Name == `T`)
+	assert(len(ns.UsingDeclarations) == 1 && ns.UsingDeclarations[0].Target == `::size_t`)
+	assert(ns.UsingDeclarations[0].Name == `size_t`)
+
+	derived := file.Classes[0]
+	assert(len(derived.Functions) == 0)
+	usings := derived.UsingDeclarations
+	assert(len(usings) == 5)
+	assert(usings[0].Name == `Base` && usings[0].Target == `Base::Base` && usings[0].Access == kPublic)
+	assert(usings[1].Name == `method` && usings[2].Name == `operator=` && usings[2].Target == `Base::operator=`)
+	assert(usings[3].Name == `operator new` && usings[3].Target == `Base::operator new`)
+	assert(usings[4].IsTypename && usings[4].Name == `value_type` && usings[4].Access == kProtected)
+	assert(len(derived.TypeAliases) == 1 && derived.TypeAliases[0].Access == kProtected)
+}
@@ -9,6 +9,10 @@ const (
 	kLiteral    Type = `kLiteral`
 	kTemplate   Type = `kTemplate`
 	kFunction   Type = `kFunction`
+	kValue      Type = `kValue`
+	kArray      Type = `kArray`
+	// Pointer to member, e.g. int Foo::* or void (Foo::*)(int)
+	kMemberPointer Type = `kMemberPointer`
 )
 
 type TypeNode struct {
@@ -33,10 +37,27 @@ type TypeNode struct {
 
 	// LiteralNode
 	LiteralName string `json:",omitempty"`
-
-	// FunctionNode
-	FunctionReturns   *TypeNode    `json:",omitempty"`
-	FunctionArguments [] *Argument `json:",omitempty"`
+	// Type constraint of a placeholder, e.g. the std::integral of std::integral auto
+	Constraint *TypeNode `json:",omitempty"`
+
+	// ValueNode, a non-type template argument such as the 4 of std::array<int, 4>
+	Value       string      `json:",omitempty"`
+	ParsedValue *Expression `json:",omitempty"`
+
+	// ArrayNode, int values[4][2] is an array of 4 arrays of 2 ints
+	ArrayBase       *TypeNode   `json:",omitempty"`
+	ArraySize       string      `json:",omitempty"` // Empty for an unknown bound, e.g. int values[]
+	ParsedArraySize *Expression `json:",omitempty"`
+
+	// MemberPointerNode, the class of int Foo::* is Foo
+	MemberPointerBase  *TypeNode `json:",omitempty"`
+	MemberPointerClass string    `json:",omitempty"`
+
+	// FunctionNode, IsConst and IsVolatile are the qualifiers of a member function type, e.g. the const
+	// of void (Foo::*)() const
+	FunctionReturns    *TypeNode   `json:",omitempty"`
+	FunctionArguments  []*Argument `json:",omitempty"`
+	FunctionIsNoexcept bool        `json:",omitempty"`
 }
 
 func NewPointerNode(b *TypeNode) *TypeNode {
@@ -60,6 +81,24 @@ func NewLReferenceNode(b *TypeNode) *TypeNode {
 	}
 }
 
+func NewMemberPointerNode(b *TypeNode, class string) *TypeNode {
+	return &TypeNode{
+		NodeType:           kMemberPointer,
+		MemberPointerBase:  b,
+		MemberPointerClass: class,
+	}
+}
+
+func NewArrayNode(b *TypeNode, size string) *TypeNode {
+	node := &TypeNode{
+		NodeType:  kArray,
+		ArrayBase: b,
+		ArraySize: size,
+	}
+	node.ParsedArraySize, _ = ParseExpression(size)
+	return node
+}
+
 func NewTemplateNode(name string) *TypeNode {
 	return &TypeNode{
 		NodeType:     kTemplate,
@@ -75,8 +114,21 @@ func NewLiteralNode(name string) *TypeNode {
 }
 
 type Argument struct {
-	Name string
-	Type *TypeNode
+	Name               string
+	Type               *TypeNode
+	DefaultValue       string       `json:",omitempty"`
+	ParsedDefaultValue *Expression  `json:",omitempty"` // nil if the default value is no constant expression
+	Comment            string       `json:",omitempty"` // Description of the parameter in the documentation of the function
+	Attributes         []*Attribute `json:",omitempty"` // [[maybe_unused]] int unused
+}
+
+func NewValueNode(value string) *TypeNode {
+	node := &TypeNode{
+		NodeType: kValue,
+		Value:    value,
+	}
+	node.ParsedValue, _ = ParseExpression(value)
+	return node
 }
 
 func NewFunctionNode() *TypeNode {
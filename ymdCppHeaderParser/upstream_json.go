@@ -0,0 +1,319 @@
+package ymdCppHeaderParser
+
+import "sort"
+
+// MarshalUpstreamJson returns the file as a json array of include, class, enum, function and property entries,
+// modelled on the description of the C++ header-parser tool (https://github.com/baszalmstra/header-parser).
+// The output is not verified against that tool, so it is not guaranteed to match its key names or layout.
+// An empty indent produces compact output.
+func MarshalUpstreamJson(file *File, indent string) string {
+	return marshalJsonIndent(upstreamMembers(&file.Members, file.Includes, false), indent)
+}
+
+type upstreamEntry struct {
+	startPos int
+	object   jsonObject
+}
+
+func upstreamMembers(members *Members, includes []*Include, inClass bool) []jsonObject {
+	var entries []upstreamEntry
+	for _, one := range includes {
+		var object jsonObject
+		object.set(`type`, `include`)
+		object.set(`file`, one.Path)
+		entries = append(entries, upstreamEntry{startPos: one.startPos, object: object})
+	}
+	for _, one := range members.Namespaces {
+		var object jsonObject
+		object.set(`type`, `namespace`)
+		object.set(`name`, one.Name)
+		object.set(`members`, upstreamMembers(&one.Members, nil, false))
+		entries = append(entries, upstreamEntry{startPos: one.startPos, object: object})
+	}
+	for _, one := range members.Classes {
+		if one.IsAnonymous {
+			// Its fields are listed with the enclosing members
+			continue
+		}
+		entries = append(entries, upstreamEntry{startPos: one.startPos, object: upstreamClass(one, inClass)})
+	}
+	for _, one := range members.Enums {
+		entries = append(entries, upstreamEntry{startPos: one.startPos, object: upstreamEnum(one, inClass)})
+	}
+	for _, one := range members.Functions {
+		entries = append(entries, upstreamEntry{startPos: one.startPos, object: upstreamFunction(one, inClass)})
+	}
+	for _, one := range members.Fields {
+		entries = append(entries, upstreamEntry{startPos: one.startPos, object: upstreamProperty(one, inClass)})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].startPos < entries[j].startPos
+	})
+
+	list := []jsonObject{}
+	for _, one := range entries {
+		list = append(list, one.object)
+	}
+	return list
+}
+
+func upstreamAccess(object *jsonObject, access AccessControlType, inClass bool) {
+	// Writing access is not required if the current scope is not owned by a class
+	if !inClass {
+		return
+	}
+	switch access {
+	case kPublic:
+		object.set(`access`, `public`)
+	case kProtected:
+		object.set(`access`, `protected`)
+	case kPrivate:
+		object.set(`access`, `private`)
+	}
+}
+
+func upstreamClass(class *Class, inClass bool) jsonObject {
+	var object jsonObject
+	object.set(`type`, `class`)
+	object.set(`line`, class.Line)
+	upstreamAccess(&object, class.Access, inClass)
+	object.set(`meta`, upstreamMeta(class.Meta))
+	object.set(`isstruct`, class.IsStruct)
+	object.set(`name`, class.Name)
+	if len(class.Bases) != 0 {
+		parents := []jsonObject{}
+		for _, one := range class.Bases {
+			var parent jsonObject
+			upstreamAccess(&parent, one.Access, true)
+			parent.set(`name`, upstreamType(one.Type))
+			parents = append(parents, parent)
+		}
+		object.set(`parents`, parents)
+	}
+	object.set(`members`, upstreamMembers(&class.Members, nil, true))
+	return object
+}
+
+func upstreamEnum(enum *Enum, inClass bool) jsonObject {
+	var object jsonObject
+	object.set(`type`, `enum`)
+	object.set(`line`, enum.Line)
+	upstreamAccess(&object, enum.Access, inClass)
+	object.set(`meta`, upstreamMeta(enum.Meta))
+	object.set(`cxxclass`, enum.IsClass)
+	object.set(`name`, enum.Name)
+	if enum.BaseType != `` {
+		object.set(`base`, enum.BaseType)
+	}
+	members := []jsonObject{}
+	for _, one := range enum.Enumerators {
+		var member jsonObject
+		member.set(`key`, one.Name)
+		if one.Expression != `` {
+			// The tokens of the value are glued together
+			value := ``
+			for _, token := range upstreamTokens(one.Expression) {
+				value += token.Mtoken
+			}
+			member.set(`value`, value)
+		}
+		members = append(members, member)
+	}
+	object.set(`members`, members)
+	return object
+}
+
+func upstreamFunction(function *Function, inClass bool) jsonObject {
+	var object jsonObject
+	object.set(`type`, `function`)
+	if function.Macro != `` {
+		object.set(`macro`, function.Macro)
+	}
+	object.set(`line`, function.Line)
+	upstreamAccess(&object, function.Access, inClass)
+	object.set(`meta`, upstreamMeta(function.Meta))
+	if function.IsVirtual {
+		object.set(`virtual`, true)
+	}
+	if function.IsInline {
+		object.set(`inline`, true)
+	}
+	if function.IsConstExpr {
+		object.set(`constexpr`, true)
+	}
+	if function.IsStatic {
+		object.set(`static`, true)
+	}
+	if function.ReturnType != nil {
+		object.set(`returnType`, upstreamType(function.ReturnType))
+	}
+	object.set(`name`, function.Name)
+	arguments := []jsonObject{}
+	for _, one := range function.Arguments {
+		var argument jsonObject
+		argument.set(`type`, upstreamType(one.Type))
+		argument.set(`name`, one.Name)
+		if one.DefaultValue != `` {
+			argument.set(`defaultValue`, upstreamValue(one.DefaultValue))
+		}
+		arguments = append(arguments, argument)
+	}
+	object.set(`arguments`, arguments)
+	if function.IsConst {
+		object.set(`const`, true)
+	}
+	if function.IsPure {
+		object.set(`abstract`, true)
+	}
+	return object
+}
+
+func upstreamProperty(field *Field, inClass bool) jsonObject {
+	var object jsonObject
+	object.set(`type`, `property`)
+	object.set(`line`, field.Line)
+	object.set(`meta`, upstreamMeta(field.Meta))
+	upstreamAccess(&object, field.Access, inClass)
+	if field.IsMutable {
+		object.set(`mutable`, true)
+	}
+	if field.IsStatic {
+		object.set(`static`, true)
+	}
+	object.set(`dataType`, upstreamType(field.Type))
+	object.set(`name`, field.Name)
+	return object
+}
+
+func upstreamType(node *TypeNode) jsonObject {
+	var object jsonObject
+	if node.IsConst {
+		object.set(`const`, true)
+	}
+	if node.IsVolatile {
+		object.set(`volatile`, true)
+	}
+	if node.IsMutable {
+		object.set(`mutable`, true)
+	}
+	switch node.NodeType {
+	case kPointer:
+		object.set(`type`, `pointer`)
+		object.set(`baseType`, upstreamType(node.PointerBase))
+	case kReference:
+		object.set(`type`, `reference`)
+		object.set(`baseType`, upstreamType(node.ReferenceBase))
+	case kLReference:
+		object.set(`type`, `lreference`)
+		object.set(`baseType`, upstreamType(node.LReferenceBase))
+	case kLiteral:
+		object.set(`type`, `literal`)
+		object.set(`name`, node.LiteralName)
+	case kTemplate:
+		object.set(`type`, `template`)
+		object.set(`name`, node.TemplateName)
+		arguments := []jsonObject{}
+		for _, one := range node.TemplateArguments {
+			arguments = append(arguments, upstreamType(one))
+		}
+		object.set(`arguments`, arguments)
+	case kArray:
+		object.set(`type`, `array`)
+		object.set(`size`, node.ArraySize)
+		object.set(`baseType`, upstreamType(node.ArrayBase))
+	case kMemberPointer:
+		object.set(`type`, `memberPointer`)
+		object.set(`className`, node.MemberPointerClass)
+		object.set(`baseType`, upstreamType(node.MemberPointerBase))
+	case kFunction:
+		object.set(`type`, `function`)
+		object.set(`returnType`, upstreamType(node.FunctionReturns))
+		arguments := []jsonObject{}
+		for _, one := range node.FunctionArguments {
+			var argument jsonObject
+			argument.set(`name`, one.Name)
+			argument.set(`type`, upstreamType(one.Type))
+			arguments = append(arguments, argument)
+		}
+		object.set(`arguments`, arguments)
+	}
+	return object
+}
+
+func upstreamMeta(meta *MetaValue) interface{} {
+	if meta == nil {
+		return jsonObject{}
+	}
+	switch meta.MetaType {
+	case kMetaConst:
+		switch meta.ConstType {
+		case kString:
+			return meta.StringConst
+		case kBoolean:
+			return meta.BoolConst
+		case kInt64:
+			return meta.Int64Const
+		case kFloat64:
+			return meta.Float64Const
+		case kChar:
+			return string(rune(meta.Int64Const))
+		}
+	case kMetaIdentifier:
+		return meta.Identifier
+	case kMetaList:
+		items := []interface{}{}
+		for _, one := range meta.Items {
+			items = append(items, upstreamMeta(one))
+		}
+		return items
+	}
+	object := jsonObject{}
+	for _, one := range meta.Entries {
+		if one.Value == nil {
+			object.set(one.Key, nil)
+		} else {
+			object.set(one.Key, upstreamMeta(one.Value))
+		}
+	}
+	return object
+}
+
+// upstreamValue writes a single constant with its json type, anything else as the glued tokens
+func upstreamValue(expression string) interface{} {
+	tokens := upstreamTokens(expression)
+	if len(tokens) == 1 && tokens[0].MtokenType == kConst {
+		return upstreamConst(&tokens[0])
+	}
+	value := ``
+	for _, one := range tokens {
+		value += one.Mtoken
+	}
+	return value
+}
+
+func upstreamConst(token *Token) interface{} {
+	switch token.MconstType {
+	case kString:
+		return token.MstringConst
+	case kBoolean:
+		return token.MboolConst
+	case kInt64:
+		return token.Mint64Const
+	case kFloat64:
+		return token.Mfloat64Const
+	case kChar:
+		return string(rune(token.Mint64Const))
+	}
+	return token.Mtoken
+}
+
+func upstreamTokens(expression string) []Token {
+	var tokens []Token
+	tn := NewTokenizer([]byte(expression), 1)
+	var token Token
+	for tn.GetToken(&token, false, false) {
+		tokens = append(tokens, token)
+		token = Token{}
+	}
+	return tokens
+}
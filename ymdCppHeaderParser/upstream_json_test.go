@@ -0,0 +1,56 @@
+package ymdCppHeaderParser
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestMarshalUpstreamJson(t *testing.T) {
+	checkUpstreamGolden(t, filepath.Join(`testdata`, `upstream_json`, `example1.h`), ParserOptions{})
+}
+
+func TestMarshalUpstreamJson_Annotated(t *testing.T) {
+	checkUpstreamGolden(t, filepath.Join(`testdata`, `upstream_json`, `annotated.h`), ParserOptions{
+		ClassNameMacro:    `TCLASS`,
+		EnumNameMacro:     `TENUM`,
+		FunctionNameMacro: `TFUNC`,
+		PropertyNameMacro: `TPROPERTY`,
+		AnnotatedOnly:     true,
+	})
+}
+
+// checkUpstreamGolden compares the output with the golden file next to the header. The golden files are
+// regression data written with -update, they only guard the output of this package against changes.
+func checkUpstreamGolden(t *testing.T, header string, options ParserOptions) {
+	input, err := ioutil.ReadFile(header)
+	assert(err == nil, err)
+	p := NewParserWithOptions(input, options)
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	got := MarshalUpstreamJson(file, `    `) + "\n"
+
+	golden := strings.TrimSuffix(header, `.h`) + `.json`
+	if *updateGolden {
+		assert(ioutil.WriteFile(golden, []byte(got), 0644) == nil)
+	}
+	want, err := ioutil.ReadFile(golden)
+	assert(err == nil, err)
+	if got != string(want) {
+		t.Errorf("%v: output differs from %v\n%v", header, golden, got)
+	}
+}
+
+func TestMarshalUpstreamJson_Compact(t *testing.T) {
+	p := NewParser([]byte(`#include "a.h"
+enum class E : int { A = 1 << 2, B };`))
+	file, err := p.ParseAll()
+	assert(err == nil, err)
+	got := MarshalUpstreamJson(file, ``)
+	want := `[{"type":"include","file":"a.h"},{"type":"enum","line":2,"meta":{},"cxxclass":true,"name":"E","base":"int","members":[{"key":"A","value":"1<<2"},{"key":"B"}]}]`
+	assert(got == want, got)
+}